@@ -1,22 +1,75 @@
 package benchmark
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"log"
+	"math"
 	"math/rand/v2"
-	"os"
-	"os/signal"
+	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
 )
 
+const (
+	// Latencies are recorded in nanoseconds with 3 significant digits.
+	// Values above one hour are recorded as one hour.
+	histogramMin     = 1
+	histogramMax     = int64(time.Hour)
+	histogramSigFigs = 3
+
+	// maxErrorClasses limits the number of distinct error classes in a result,
+	// further classes are counted as "other".
+	maxErrorClasses = 100
+)
+
+// ErrStmtTimeout is recorded when a statement exceeded the statement timeout.
+var ErrStmtTimeout = errors.New("statement timeout")
+
+// ErrArgsNotSupported is recorded when a statement with bind parameters
+// is executed by a bencher which doesn't implement ArgExecutor.
+var ErrArgsNotSupported = errors.New("bind parameters are not supported by the database")
+
+// literalRegexp matches string literals in error messages, e.g. the key of a
+// duplicate entry, which differ for every failed statement.
+var literalRegexp = regexp.MustCompile(`'[^']*'`)
+
 // Bencher is the interface a benchmark has to impelement.
 type Bencher interface {
 	Setup()
 	Cleanup()
 	Benchmarks() []Benchmark
-	Exec(string)
+	Exec(context.Context, string) error
+}
+
+// Executor executes a single statement, e.g. a Bencher or a Session.
+type Executor interface {
+	Exec(context.Context, string) error
+}
+
+// ArgExecutor is implemented by executors which support bind parameters,
+// the arguments are sent to the database separately from the statement.
+type ArgExecutor interface {
+	ExecArgs(ctx context.Context, stmt string, args []any) error
+}
+
+// Sessioner is implemented by benchers which can execute several statements
+// on the same connection, e.g. a transaction spread over several statements.
+type Sessioner interface {
+	Session(context.Context) (Session, error)
+}
+
+// Session executes statements on a single connection until it's closed.
+type Session interface {
+	Executor
+	Close() error
 }
 
 // BenchType determines if the particular benchmark should be run several times or only once.
@@ -29,15 +82,87 @@ const (
 	TypeOnce BenchType = iota
 )
 
+func (t BenchType) String() string {
+	switch t {
+	case TypeLoop:
+		return "loop"
+	case TypeOnce:
+		return "once"
+	}
+	return "unknown"
+}
+
 // Benchmark contains the benchmark name, its db statement and its type.
+// Consecutive benchmarks with Parallel set are executed concurrently (see Group).
+// Iter, Threads and Duration override the Options of the run when set.
+// Sleep overrides the pause after the benchmark.
+// A loop benchmark with Mix executes one of the mixed statements each
+// iteration instead of Stmt.
+// Stmt may consist of several statements separated by the Delimiter, which
+// are executed one after another in each iteration. With Breakdown, the
+// metrics of each of the statements are recorded as well.
+// ThinkTime pauses each thread after each iteration of a loop benchmark,
+// the pause isn't measured.
 type Benchmark struct {
-	Name     string
-	Type     BenchType
-	Parallel bool
-	Stmt     string
+	Name      string
+	Type      BenchType
+	Parallel  bool
+	Stmt      string
+	Mix       []MixStmt
+	Vars      []Var // evaluated in order before each iteration
+	Iter      int
+	Threads   int
+	Duration  time.Duration
+	Sleep     time.Duration
+	Delimiter string // ';' when empty
+	Breakdown bool
+	ThinkTime time.Duration
+}
+
+// Statements returns the statements of Stmt, split at the Delimiter.
+func (b Benchmark) Statements() []string {
+	return splitAll(b.Stmt, b.Delimiter)
+}
+
+// options returns the options for running the benchmark,
+// the settings of the benchmark take precedence over the given options.
+func (b Benchmark) options(opts Options) Options {
+	if b.Iter > 0 {
+		opts.Iter = b.Iter
+		// a fixed number of iterations replaces the duration of the run
+		opts.Duration = b.Duration
+	}
+	if b.Duration > 0 {
+		opts.Duration = b.Duration
+	}
+	if b.Threads > 0 {
+		opts.Threads = b.Threads
+	}
+
+	// can't have more threads than iterations
+	if opts.Duration == 0 && opts.Threads > opts.Iter {
+		opts.Threads = opts.Iter
+	}
+	return opts
 }
 
-// Result encapsulates the metrics of a benchmark run
+// Group splits the benchmarks into the groups which are executed together.
+// Consecutive parallel benchmarks form a group, every other benchmark
+// is in a group of its own.
+func Group(benchmarks []Benchmark) [][]Benchmark {
+	groups := [][]Benchmark{}
+	for i, b := range benchmarks {
+		if b.Parallel && i > 0 && benchmarks[i-1].Parallel {
+			groups[len(groups)-1] = append(groups[len(groups)-1], b)
+			continue
+		}
+		groups = append(groups, []Benchmark{b})
+	}
+	return groups
+}
+
+// Result encapsulates the metrics of a benchmark run.
+// Latencies only cover successfully executed statements.
 type Result struct {
 	Min                 time.Duration
 	Max                 time.Duration
@@ -45,154 +170,739 @@ type Result struct {
 	Start               time.Time
 	End                 time.Time
 	Duration            time.Duration
-	TotalExecutionCount uint64
+	TotalExecutionCount uint64 // successful and failed executions
+	SuccessCount        uint64
+	ErrorCount          uint64
+	Errors              map[string]uint64 // number of failed executions by error class
+	Threads             int               // number of threads which executed the benchmark
+	Intervals           []Interval        // metrics of each reporting interval (see Options.ReportInterval)
+	Mix                 []Result          // metrics of each mixed statement, in the order of Benchmark.Mix
+	Statements          []Result          // metrics of each statement of the iteration, see Benchmark.Breakdown
+
+	histogram *hdrhistogram.Histogram
 }
 
 // Avg calculates the results average
 func (r Result) Avg() time.Duration {
-	if r.TotalExecutionCount == 0 {
+	if r.SuccessCount == 0 {
+		return 0
+	}
+	return time.Duration(int64(r.TotalExecutionTime) / int64(r.SuccessCount))
+}
+
+// Percentile returns the latency at the given percentile (0-100), e.g. 99.9.
+func (r Result) Percentile(p float64) time.Duration {
+	if r.histogram == nil {
 		return 0
 	}
-	return time.Duration(int64(r.TotalExecutionTime) / int64(r.TotalExecutionCount))
+	return time.Duration(r.histogram.ValueAtPercentile(p))
+}
+
+// StdDev returns the standard deviation of the latencies.
+func (r Result) StdDev() time.Duration {
+	if r.histogram == nil {
+		return 0
+	}
+	return time.Duration(r.histogram.StdDev())
+}
+
+// newResult returns an empty result with an initialized latency histogram.
+func newResult() Result {
+	return Result{
+		Start:     time.Now(),
+		Errors:    map[string]uint64{},
+		histogram: newHistogram(),
+	}
+}
+
+// newResults returns n empty results.
+func newResults(n int) []Result {
+	results := make([]Result, n)
+	for i := range results {
+		results[i] = newResult()
+	}
+	return results
+}
+
+// newHistogram returns a histogram for latencies.
+func newHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs)
+}
+
+// Options configures how a benchmark is executed.
+type Options struct {
+	// Iter is the number of iterations of a loop benchmark.
+	Iter int
+	// Threads is the number of concurrent routines of a loop benchmark.
+	Threads int
+	// Seed initializes the random generators of the templates and variables.
+	// Each thread has its own generator, derived from the seed and the thread
+	// number, a run with the same seed and a single thread is reproducible.
+	Seed uint64
+	// RunID identifies the run in the templates, e.g. to tag the written rows.
+	RunID string
+	// Placeholder returns the placeholder of the nth bind parameter (starting
+	// at 1) of a statement, e.g. '$1' for PostgreSQL. Nil uses '?'.
+	Placeholder func(n int) string
+	// Duration runs a loop benchmark until the duration passed instead of
+	// a fixed number of iterations.
+	Duration time.Duration
+	// Rate is the target throughput in statements per second of a loop
+	// benchmark. Zero executes the statements as fast as possible.
+	Rate float64
+	// StmtTimeout aborts statements which take longer. Zero disables the timeout.
+	StmtTimeout time.Duration
+	// WarmupIter and WarmupDuration execute a loop benchmark for the given
+	// iterations or duration before the metrics are recorded.
+	WarmupIter     int
+	WarmupDuration time.Duration
+	// ReportInterval collects the metrics of loop benchmarks additionally
+	// in intervals of the given duration. Zero disables the intervals.
+	ReportInterval time.Duration
+	// OnInterval is called with the name of the benchmark after each interval.
+	OnInterval func(name string, interval Interval)
 }
 
 // bencherExecutor is responsible for running the benchmark, keeping track
 // of metrics as the execution goes
 type bencherExecutor struct {
-	result Result
-	mux    sync.Mutex
+	result    Result
+	mux       sync.Mutex
+	iter      atomic.Int64   // the last iteration handed out to a routine
+	discard   bool           // don't record metrics, e.g. during the warmup
+	breakdown bool           // record the metrics of each statement, see Benchmark.Breakdown
+	think     time.Duration  // pause after each iteration, see Benchmark.ThinkTime
+	threads   []*thread      // the state of each routine, continued by the next phase
+	name      string         // the name of the benchmark, passed to Options.OnInterval
+	runID     string         // see Options.RunID
+	start     time.Time      // the start of the benchmark, including the warmup
+	totalIter int            // the iterations of the benchmark, see totalIter
+	interval  *intervalStats // metrics of the current interval, when reporting intervals
+}
+
+// thread is the state of a routine, the thread number starts at 1.
+type thread struct {
+	rand *rand.Rand // see Options.Seed
+	iter int        // the number of iterations handed out to the routine
 }
 
-// Run executes the benchmark.
-func Run(bencher Bencher, b Benchmark, iter, threads int) Result {
-	t := template.New(b.Name)
-	t, err := t.Parse(b.Stmt)
+// Run executes the benchmark. Canceling the context stops the benchmark
+// and aborts the statements in flight.
+func Run(ctx context.Context, bencher Bencher, b Benchmark, opts Options) Result {
+	t, err := newStatements(b)
 	if err != nil {
 		log.Fatalf("failed to parse template: %v", err)
 	}
 
-	executor := bencherExecutor{
-		result: Result{
-			Start: time.Now(),
-		},
+	executor := bencherExecutor{result: newResult(), name: b.Name, breakdown: b.Breakdown, think: b.ThinkTime}
+	for _, m := range b.Mix {
+		mix := newResult()
+		if b.Breakdown {
+			mix.Statements = newResults(len(Benchmark{Stmt: m.Stmt, Delimiter: b.Delimiter}.Statements()))
+		}
+		executor.result.Mix = append(executor.result.Mix, mix)
+	}
+	if b.Breakdown && len(b.Mix) == 0 {
+		executor.result.Statements = newResults(len(b.Statements()))
 	}
+	opts = b.options(opts)
+	t.placeholder = opts.Placeholder
+	executor.runID, executor.start, executor.totalIter = opts.RunID, time.Now(), totalIter(b.Type, opts)
 
 	switch b.Type {
 	case TypeOnce:
-		if b.Parallel {
-			go executor.once(bencher, t)
-		} else {
-			executor.once(bencher, t)
-		}
+		executor.result.Threads = 1
+		executor.once(ctx, bencher, t, opts)
 	case TypeLoop:
-		if b.Parallel {
-			go executor.loop(bencher, t, iter, threads)
-		} else {
-			executor.loop(bencher, t, iter, threads)
+		executor.result.Threads = opts.Threads
+		if opts.WarmupIter > 0 || opts.WarmupDuration > 0 {
+			executor.warmup(ctx, bencher, t, opts)
+			executor.result.Start = time.Now()
 		}
+		executor.loop(ctx, bencher, t, opts)
 	}
 
-	executor.result.End = time.Now()
-	executor.result.Duration = time.Since(executor.result.Start)
-
+	executor.result.finish(executor.result.Start, time.Now())
 	return executor.result
 }
 
+// finish sets the end and the duration of the result and its mixed
+// statements and statements, which all share the start of the benchmark.
+func (r *Result) finish(start, end time.Time) {
+	r.Start, r.End, r.Duration = start, end, end.Sub(start)
+	for i := range r.Mix {
+		r.Mix[i].finish(start, end)
+	}
+	for i := range r.Statements {
+		r.Statements[i].finish(start, end)
+	}
+}
+
+// Exec builds the statement and executes it once without measuring it,
+// e.g. for the setup and teardown statements of a script. The name, e.g.
+// "setup 1", derives the random generator of the statement from the seed.
+func Exec(ctx context.Context, bencher Bencher, stmt, name string, opts Options) error {
+	stmt, err := Render(stmt, name, opts)
+	if err != nil {
+		return err
+	}
+	return execStmt(ctx, bencher, query{stmt: stmt}, opts.StmtTimeout)
+}
+
+// Render builds the statement like Exec without executing it.
+func Render(stmt, name string, opts Options) (string, error) {
+	t, err := parseTemplate(name, stmt)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %v", err)
+	}
+	it := firstIteration(newRand(benchSeed(opts.Seed, name), 1))
+	it.benchName, it.runID, it.start = name, opts.RunID, time.Now()
+	stmt, args, err := renderStmt(t, it, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute template: %v", err)
+	}
+	if len(args) > 0 {
+		return "", errArgsNotInBenchmark
+	}
+	return stmt, nil
+}
+
+// RunParallel executes the benchmarks concurrently and waits until all of
+// them finished. The results are returned in the order of the benchmarks.
+func RunParallel(ctx context.Context, bencher Bencher, benchmarks []Benchmark, opts Options) []Result {
+	results := make([]Result, len(benchmarks))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(benchmarks))
+
+	for i, b := range benchmarks {
+		go func() {
+			defer wg.Done()
+			results[i] = Run(ctx, bencher, b, opts)
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// phase is a single execution of the loop, e.g. the warmup or the measurement.
+type phase struct {
+	iter     int // number of iterations, when no duration is set
+	duration time.Duration
+	rate     float64
+	start    time.Time
+	deadline time.Time
+	offset   int // the last iteration of the previous phase
+}
+
+func newPhase(iter int, duration time.Duration, rate float64, offset int) phase {
+	start := time.Now()
+
+	// When rate limited, the number of iterations which fit into the duration is known.
+	if rate > 0 && duration > 0 {
+		iter = int(math.Ceil(duration.Seconds() * rate))
+	}
+
+	return phase{
+		iter:     iter,
+		duration: duration,
+		rate:     rate,
+		start:    start,
+		deadline: start.Add(duration),
+		offset:   offset,
+	}
+}
+
+// next returns the next iteration to execute and the time it is supposed
+// to start. It returns false when the phase is done, either because all
+// iterations were handed out or because the deadline passed.
+func (b *bencherExecutor) next(p phase) (int, time.Time, bool) {
+	if p.duration > 0 && p.rate <= 0 {
+		// Check the deadline before handing out the iteration,
+		// this way the executed iterations don't have any gaps.
+		if !time.Now().Before(p.deadline) {
+			return 0, time.Time{}, false
+		}
+		return int(b.iter.Add(1)), time.Now(), true
+	}
+
+	// Never hand out more iterations than the phase has,
+	// the next phase continues with the following iteration.
+	for {
+		last := b.iter.Load()
+		if int(last)-p.offset >= p.iter {
+			return 0, time.Time{}, false
+		}
+		if !b.iter.CompareAndSwap(last, last+1) {
+			continue
+		}
+
+		i := int(last + 1)
+		if p.rate > 0 {
+			// Open-loop: each iteration has a fixed slot on the arrival timeline,
+			// independent of how long the previous statements took.
+			return i, p.start.Add(time.Duration(float64(i-p.offset-1) * float64(time.Second) / p.rate)), true
+		}
+		return i, time.Now(), true
+	}
+}
+
+// warmup runs the benchmark like loop, but without recording any metrics.
+// The iterations of the following loop continue after the warmup iterations.
+func (b *bencherExecutor) warmup(ctx context.Context, bencher Bencher, t statements, opts Options) {
+	b.discard = true
+	defer func() { b.discard = false }()
+
+	b.run(ctx, bencher, t, opts, newPhase(opts.WarmupIter, opts.WarmupDuration, opts.Rate, int(b.iter.Load())))
+}
+
 // loop runs the benchmark concurrently several times.
-func (b *bencherExecutor) loop(bencher Bencher, t *template.Template, iterations, threads int) {
+func (b *bencherExecutor) loop(ctx context.Context, bencher Bencher, t statements, opts Options) {
+	if opts.ReportInterval > 0 {
+		stop := b.reportIntervals(opts)
+		defer stop()
+	}
+
+	b.run(ctx, bencher, t, opts, newPhase(opts.Iter, opts.Duration, opts.Rate, int(b.iter.Load())))
+}
+
+// run executes the phase with the configured number of routines.
+func (b *bencherExecutor) run(ctx context.Context, bencher Bencher, t statements, opts Options, p phase) {
+	if p.duration > 0 {
+		// abort the statements which are still running when the time is up
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, p.deadline)
+		defer cancel()
+	}
+
+	// the phases continue with the random generators of the previous phase
+	if len(b.threads) < opts.Threads {
+		b.threads = newThreads(benchSeed(opts.Seed, b.name), opts.Threads)
+	}
+
 	wg := &sync.WaitGroup{}
-	wg.Add(threads)
+	wg.Add(opts.Threads)
 	defer wg.Wait()
 
 	// start as many routines as specified
-	for routine := 0; routine < threads; routine++ {
-		// calculate the amount of iterations to execute in this routine
-		from := ((iterations / threads) * routine) + 1
-		to := (iterations / threads) * (routine + 1)
-
-		// Add the remainder of iterations to the last routine.
-		if routine == threads-1 {
-			remainder := iterations - to
-			to += remainder
-		}
-
-		// start the routine
-		go func(gofrom, togo int) {
+	for routine := 0; routine < opts.Threads; routine++ {
+		th := b.threads[routine]
+		go func() {
 			defer wg.Done()
-			// notify channel for SIGINT (ctrl-c)
-			sigchan := make(chan os.Signal, 1)
-			signal.Notify(sigchan, os.Interrupt)
-
-			for i := gofrom; i <= togo; i++ {
-				select {
-				case <-sigchan:
-					// got SIGINT, stop benchmarking
+
+			for {
+				i, at, ok := b.next(p)
+				if !ok {
+					return
+				}
+
+				// wait for the scheduled start when rate limited
+				if wait := time.Until(at); wait > 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(wait):
+					}
+				}
+
+				if ctx.Err() != nil {
+					// canceled, stop benchmarking
 					return
-				default:
-					// build and execute the statement
-					stmt := buildStmt(t, i)
-					now := time.Now()
-					bencher.Exec(stmt)
-					b.collectStats(now)
+				}
+
+				// build and execute the statement
+				th.iter++
+				stmt, mix, err := t.build(iteration{
+					iter:       i,
+					thread:     routine + 1,
+					threadIter: th.iter,
+					threads:    opts.Threads,
+					totalIter:  b.totalIter,
+					benchName:  b.name,
+					runID:      b.runID,
+					start:      b.start,
+					rand:       th.rand,
+				})
+				if p.rate <= 0 {
+					at = time.Now()
+				}
+				// When rate limited, the latency is measured from the scheduled
+				// start, so stalls are not hidden by the delayed statements
+				// (coordinated omission).
+				if err != nil {
+					b.fail(ctx, i, mix, at, err)
+				} else {
+					b.exec(ctx, bencher, stmt, mix, at, opts.StmtTimeout)
+				}
+
+				if b.think > 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(b.think):
+					}
 				}
 			}
-		}(from, to)
+		}()
+	}
+}
+
+// fail records the iteration i as failed, its statements couldn't be built,
+// e.g. because of a division by zero in a variable.
+func (b *bencherExecutor) fail(ctx context.Context, i, mix int, start time.Time, err error) {
+	if ctx.Err() != nil || b.discard {
+		return
+	}
+	b.collectStats(start, fmt.Sprintf("iteration %v", i), mix, nil, err)
+}
+
+// exec executes the statements of the iteration and records their metrics,
+// mix is the index of the mixed statement. Statements which were aborted
+// because the benchmark was canceled are not recorded.
+func (b *bencherExecutor) exec(ctx context.Context, bencher Bencher, stmts []query, mix int, start time.Time, timeout time.Duration) {
+	stmt, durations, err := execStmts(ctx, bencher, stmts, timeout)
+	if ctx.Err() != nil || b.discard {
+		return
+	}
+	b.collectStats(start, stmt, mix, durations, err)
+}
+
+// execStmts executes the statements one after another and stops at the first
+// failing statement, which is returned with its error. It returns the duration
+// of each executed statement as well. Several statements are executed on the
+// same connection when the bencher supports sessions.
+func execStmts(ctx context.Context, bencher Bencher, stmts []query, timeout time.Duration) (string, []time.Duration, error) {
+	var exec Executor = bencher
+	if sessioner, ok := bencher.(Sessioner); ok && len(stmts) > 1 {
+		session, err := sessioner.Session(ctx)
+		if err != nil {
+			return joinStmts(stmts), nil, fmt.Errorf("failed to open session: %w", err)
+		}
+		defer session.Close()
+		exec = session
+	}
+
+	durations := make([]time.Duration, 0, len(stmts))
+	for _, q := range stmts {
+		start := time.Now()
+		err := execStmt(ctx, exec, q, timeout)
+		durations = append(durations, time.Since(start))
+		if err != nil {
+			return q.stmt, durations, err
+		}
+	}
+	return joinStmts(stmts), durations, nil
+}
+
+// joinStmts returns the statements of the iteration, one per line.
+func joinStmts(stmts []query) string {
+	lines := make([]string, len(stmts))
+	for i, q := range stmts {
+		lines[i] = q.stmt
+	}
+	return strings.Join(lines, "\n")
+}
+
+// execStmt executes a single statement with the statement timeout. The arguments
+// of bind parameters require an ArgExecutor.
+func execStmt(ctx context.Context, exec Executor, q query, timeout time.Duration) error {
+	stmtCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		stmtCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var err error
+	switch argExec, ok := exec.(ArgExecutor); {
+	case len(q.args) == 0:
+		err = exec.Exec(stmtCtx, q.stmt)
+	case ok:
+		err = argExec.ExecArgs(stmtCtx, q.stmt, q.args)
+	default:
+		err = ErrArgsNotSupported
 	}
+
+	// drivers report aborted statements differently
+	if err != nil && ctx.Err() == nil && errors.Is(stmtCtx.Err(), context.DeadlineExceeded) {
+		err = ErrStmtTimeout
+	}
+	return err
 }
 
-func (b *bencherExecutor) collectStats(start time.Time) {
+// collectStats records the execution of the statement which started at the given time.
+// Executions of mixed statements are recorded in the result of the statement as well,
+// durations are the durations of the executed statements of the iteration.
+func (b *bencherExecutor) collectStats(start time.Time, stmt string, mix int, durations []time.Duration, err error) {
+	durTime := time.Since(start)
+
 	b.mux.Lock()
 	defer b.mux.Unlock()
 
-	b.result.TotalExecutionCount++
+	// only log the first occurrence of each error class
+	if first := b.result.record(durTime, err); first {
+		log.Printf("%v failed: %v", stmt, err)
+	}
+	result := &b.result
+	if len(b.result.Mix) > 0 {
+		result = &b.result.Mix[mix]
+		result.record(durTime, err)
+	}
+	if b.breakdown {
+		result.recordStatements(durations, err)
+	}
 
-	durTime := time.Since(start)
+	if b.interval != nil {
+		if err != nil {
+			b.interval.errors++
+			return
+		}
+		b.interval.successes++
+		_ = b.interval.histogram.RecordValue(min(int64(durTime), histogramMax))
+	}
+}
+
+// record adds a single execution to the result and returns true when it's
+// the first error of its class. Failed executions are counted by their error
+// class and don't affect the latencies.
+func (r *Result) record(durTime time.Duration, err error) bool {
+	r.TotalExecutionCount++
+
+	if err != nil {
+		r.ErrorCount++
 
-	b.result.TotalExecutionTime += durTime
+		if r.Errors == nil {
+			r.Errors = map[string]uint64{}
+		}
+
+		class := errorClass(err)
+		if _, ok := r.Errors[class]; !ok && len(r.Errors) >= maxErrorClasses {
+			class = "other"
+		}
+
+		r.Errors[class]++
+		return r.Errors[class] == 1
+	}
+
+	r.SuccessCount++
+
+	r.TotalExecutionTime += durTime
+
+	if durTime > r.Max {
+		r.Max = durTime
+	}
 
-	if durTime > b.result.Max {
-		b.result.Max = durTime
+	if durTime < r.Min || r.Min == 0 {
+		r.Min = durTime
 	}
 
-	if durTime < b.result.Min || b.result.Min == 0 {
-		b.result.Min = durTime
+	// RecordValue only fails for values out of range, which are capped beforehand.
+	_ = r.histogram.RecordValue(min(int64(durTime), histogramMax))
+	return false
+}
+
+// recordStatements adds the executed statements of an iteration to the
+// results of the statements. Only the last statement failed when err is set.
+func (r *Result) recordStatements(durations []time.Duration, err error) {
+	for i, d := range durations {
+		if i >= len(r.Statements) {
+			// the template rendered more statements than the script contains
+			r.Statements = append(r.Statements, newResult())
+		}
+
+		var stmtErr error
+		if i == len(durations)-1 {
+			stmtErr = err
+		}
+		r.Statements[i].record(d, stmtErr)
 	}
 }
 
 // once runs the benchmark a single time.
-func (b *bencherExecutor) once(bencher Bencher, t *template.Template) {
-	stmt := buildStmt(t, 1)
-	defer b.collectStats(time.Now())
-	bencher.Exec(stmt)
+func (b *bencherExecutor) once(ctx context.Context, bencher Bencher, t statements, opts Options) {
+	it := firstIteration(newRand(benchSeed(opts.Seed, b.name), 1))
+	it.benchName, it.runID, it.start = b.name, b.runID, b.start
+	stmt, mix, err := t.build(it)
+	if err != nil {
+		b.fail(ctx, 1, mix, time.Now(), err)
+		return
+	}
+	b.exec(ctx, bencher, stmt, mix, time.Now(), opts.StmtTimeout)
+}
+
+// errorClass groups similar errors by masking the string literals
+// in the error message.
+func errorClass(err error) string {
+	return literalRegexp.ReplaceAllString(err.Error(), "'?'")
+}
+
+// parseTemplate parses the statement template. Accessing undefined variables fails.
+func parseTemplate(name, stmt string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(stmt)
+}
+
+// benchSeed derives the seed of the benchmark from the seed of the run, the
+// benchmarks of a run, e.g. parallel ones, don't draw the same values.
+func benchSeed(seed uint64, name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return seed ^ h.Sum64()
 }
 
-// buildStmt parses the given template with variables and functions to a pure DB statement.
-func buildStmt(t *template.Template, i int) string {
+// newRand returns the random generator of the thread (starting at 1).
+func newRand(seed uint64, thread int) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, uint64(thread)))
+}
+
+// newThreads returns the initial state of the threads.
+func newThreads(seed uint64, threads int) []*thread {
+	ths := make([]*thread, threads)
+	for i := range ths {
+		ths[i] = &thread{rand: newRand(seed, i+1)}
+	}
+	return ths
+}
+
+// totalIter returns the number of iterations of the benchmark including the
+// warmup, i.e. the last iteration. It's 0 when it isn't known in advance,
+// because a phase runs for a duration without a rate limit.
+func totalIter(typ BenchType, opts Options) int {
+	if typ == TypeOnce {
+		return 1
+	}
+	if opts.Rate <= 0 && (opts.Duration > 0 || opts.WarmupDuration > 0) {
+		return 0
+	}
+	return phaseIter(opts.WarmupIter, opts.WarmupDuration, opts.Rate) + phaseIter(opts.Iter, opts.Duration, opts.Rate)
+}
+
+// phaseIter returns the number of iterations of a phase, see newPhase.
+func phaseIter(iter int, duration time.Duration, rate float64) int {
+	if rate > 0 && duration > 0 {
+		return int(math.Ceil(duration.Seconds() * rate))
+	}
+	return iter
+}
+
+// iteration is the state the statements of an iteration are rendered with.
+type iteration struct {
+	iter       int
+	thread     int // starting at 1
+	threadIter int // the iteration of the thread, starting at 1
+	threads    int
+	totalIter  int // 0 when unknown, see totalIter
+	benchName  string
+	runID      string
+	start      time.Time // the start of the benchmark, including the warmup
+	rand       *rand.Rand
+}
+
+// firstIteration returns the first iteration of a single thread,
+// e.g. to render a statement which is executed once.
+func firstIteration(r *rand.Rand) iteration {
+	return iteration{iter: 1, thread: 1, threadIter: 1, threads: 1, totalIter: 1, start: time.Now(), rand: r}
+}
+
+// renderStmt executes the template of the iteration, the random values are
+// drawn from the random generator of the iteration. The arguments of the bind
+// parameters are returned in order, their positions are marked by argMarker.
+func renderStmt(t *template.Template, it iteration, vars map[string]any) (string, []any, error) {
 	sb := &strings.Builder{}
+	r := it.rand
+	args := []any{}
 
+	// the integers are int64 to pass them to the functions
 	data := struct {
-		Iter            int
-		RandInt64       func() int64
-		RandInt64N      func(int64) int64
-		RandUint64      func() uint64
-		RandUint64N     func(uint64) uint64
-		RandFloat32     func() float32
-		RandFloat64     func() float64
-		RandExpFloat64  func() float64
-		RandNormFloat64 func() float64
+		Iter                 int64
+		Thread               int64
+		ThreadIter           int64
+		Threads              int64
+		TotalIter            int64
+		ClientID             int64 // Thread - 1, like :client_id of pgbench
+		BenchName            string
+		RunID                string
+		Elapsed              time.Duration
+		Vars                 map[string]any
+		Arg                  func(any) string
+		RandInt64            func() int64
+		RandInt64N           func(int64) int64
+		RandUint64           func() uint64
+		RandUint64N          func(uint64) uint64
+		RandFloat32          func() float32
+		RandFloat64          func() float64
+		RandExpFloat64       func() float64
+		RandNormFloat64      func() float64
+		RandString           func(int64, ...string) (string, error)
+		RandHex              func(int64) (string, error)
+		RandChoice           func(...any) (any, error)
+		RandTime             func(string, string) (string, error)
+		UUIDv4               func() string
+		UUIDv7               func() string
+		RandFirstName        func() string
+		RandLastName         func() string
+		RandName             func() string
+		RandEmail            func() string
+		RandZipfian          func(int64, int64, float64) (int64, error)
+		RandScrambledZipfian func(int64, int64, float64) (int64, error)
+		RandHotspot          func(int64, int64, float64, float64) (int64, error)
+		RandLatest           func(int64, int64, float64) (int64, error)
 	}{
-		Iter:            i,
-		RandInt64:       rand.Int64,
-		RandInt64N:      rand.Int64N,
-		RandUint64:      rand.Uint64,
-		RandUint64N:     rand.Uint64N,
-		RandFloat32:     rand.Float32,
-		RandFloat64:     rand.Float64,
-		RandExpFloat64:  rand.ExpFloat64,
-		RandNormFloat64: rand.NormFloat64,
+		Iter:       int64(it.iter),
+		Thread:     int64(it.thread),
+		ThreadIter: int64(it.threadIter),
+		Threads:    int64(it.threads),
+		TotalIter:  int64(it.totalIter),
+		ClientID:   int64(it.thread - 1),
+		BenchName:  it.benchName,
+		RunID:      it.runID,
+		Elapsed:    time.Since(it.start),
+		Vars:       vars,
+		Arg: func(arg any) string {
+			args = append(args, arg)
+			return argMarker(len(args) - 1)
+		},
+		RandInt64:       r.Int64,
+		RandInt64N:      r.Int64N,
+		RandUint64:      r.Uint64,
+		RandUint64N:     r.Uint64N,
+		RandFloat32:     r.Float32,
+		RandFloat64:     r.Float64,
+		RandExpFloat64:  r.ExpFloat64,
+		RandNormFloat64: r.NormFloat64,
+		RandString: func(n int64, alphabet ...string) (string, error) {
+			return randString(r, n, alphabet...)
+		},
+		RandHex: func(n int64) (string, error) {
+			return randHex(r, n)
+		},
+		RandChoice: func(items ...any) (any, error) {
+			return randChoice(r, items...)
+		},
+		RandTime: func(from, to string) (string, error) {
+			return randTime(r, from, to)
+		},
+		UUIDv4:        func() string { return uuidV4(r) },
+		UUIDv7:        func() string { return uuidV7(r) },
+		RandFirstName: func() string { return randFirstName(r) },
+		RandLastName:  func() string { return randLastName(r) },
+		RandName:      func() string { return randName(r) },
+		RandEmail:     func() string { return randEmail(r) },
+		RandZipfian: func(lb, ub int64, theta float64) (int64, error) {
+			return zipfian(r, "RandZipfian", lb, ub, theta)
+		},
+		RandScrambledZipfian: func(lb, ub int64, theta float64) (int64, error) {
+			return scrambledZipfian(r, "RandScrambledZipfian", lb, ub, theta)
+		},
+		RandHotspot: func(lb, ub int64, hotKeys, hotOps float64) (int64, error) {
+			return hotspot(r, "RandHotspot", lb, ub, hotKeys, hotOps)
+		},
+		RandLatest: func(lb, ub int64, theta float64) (int64, error) {
+			return latest(r, "RandLatest", lb, ub, theta)
+		},
 	}
 	if err := t.Execute(sb, data); err != nil {
-		log.Fatalf("failed to execute template: %v", err)
+		return "", nil, err
 	}
-	return sb.String()
+	return sb.String(), args, nil
 }
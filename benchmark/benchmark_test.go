@@ -1,6 +1,13 @@
 package benchmark
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"text/template"
 	"time"
@@ -8,6 +15,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type mockedBencher struct {
@@ -17,14 +25,52 @@ type mockedBencher struct {
 func (b *mockedBencher) Benchmarks() []Benchmark { return []Benchmark{} }
 func (b *mockedBencher) Setup()                  {}
 func (b *mockedBencher) Cleanup()                {}
-func (b *mockedBencher) Exec(s string)           { _ = b.Called(s) }
+func (b *mockedBencher) Exec(_ context.Context, s string) error {
+	return b.Called(s).Error(0)
+}
+
+// blockingBencher blocks each statement until its context is done.
+type blockingBencher struct{ mockedBencher }
+
+func (b *blockingBencher) Exec(ctx context.Context, _ string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// sessionBencher executes the statements of sessions with the prefix "session:".
+type sessionBencher struct {
+	mockedBencher
+	open atomic.Int64 // number of open sessions
+}
+
+func (b *sessionBencher) Session(context.Context) (Session, error) {
+	b.open.Add(1)
+	return &mockedSession{b}, nil
+}
 
-func TestBuildStmt(t *testing.T) {
+type mockedSession struct{ b *sessionBencher }
+
+func (s *mockedSession) Exec(ctx context.Context, stmt string) error {
+	return s.b.Exec(ctx, "session:"+stmt)
+}
+
+func (s *mockedSession) Close() error {
+	s.b.open.Add(-1)
+	return nil
+}
+
+// single returns the statements of a benchmark with a single statement.
+func single(t *template.Template) statements {
+	return statements{templates: []*template.Template{t}}
+}
+
+func TestRenderStmt(t *testing.T) {
 	// arrange
 	tmpl := template.Must(template.New("test").Parse("{{.Iter}} test"))
 
 	// act
-	stmt := buildStmt(tmpl, 1337)
+	stmt, _, err := renderStmt(tmpl, iteration{iter: 1337, rand: newRand(1, 1)}, nil)
+	require.NoError(t, err)
 
 	// assert
 	want := "1337 test"
@@ -33,6 +79,33 @@ func TestBuildStmt(t *testing.T) {
 	}
 }
 
+func TestRenderStmtIteration(t *testing.T) {
+	// arrange
+	tmpl := template.Must(template.New("test").Parse(
+		"{{.Iter}} {{.Thread}} {{.ThreadIter}} {{.Threads}} {{.TotalIter}} {{.ClientID}} {{.BenchName}} {{.RunID}} {{gt .Elapsed 0}}"))
+	it := iteration{
+		iter:       7,
+		thread:     2,
+		threadIter: 3,
+		threads:    4,
+		totalIter:  100,
+		benchName:  "insert",
+		runID:      "run-1",
+		start:      time.Now().Add(-time.Second),
+		rand:       newRand(1, 1),
+	}
+
+	// act
+	stmt, _, err := renderStmt(tmpl, it, nil)
+	require.NoError(t, err)
+
+	// assert
+	want := "7 2 3 4 100 1 insert run-1 true"
+	if stmt != want {
+		t.Errorf("got statement %v, want %v", stmt, want)
+	}
+}
+
 func TestRun(t *testing.T) {
 	testCases := []struct {
 		description string
@@ -52,14 +125,14 @@ func TestRun(t *testing.T) {
 		t.Run(tt.description, func(t *testing.T) {
 			// arrange
 			bencher := &mockedBencher{}
-			bencher.On("Exec", mock.Anything)
+			bencher.On("Exec", mock.Anything).Return(nil)
 
 			iter := 13
 			threads := 5
 			bLoop := Benchmark{Name: "test", Type: tt.givenType, Stmt: "NONE"}
 
 			// act
-			Run(bencher, bLoop, iter, threads)
+			Run(context.Background(), bencher, bLoop, Options{Iter: iter, Threads: threads})
 
 			// assert
 			switch tt.givenType {
@@ -71,39 +144,509 @@ func TestRun(t *testing.T) {
 		})
 	}
 }
+func TestRunBenchmarkOptions(t *testing.T) {
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+
+	b := Benchmark{Name: "test", Type: TypeLoop, Stmt: "NONE", Iter: 7, Threads: 2}
+
+	// the global duration is replaced by the iterations of the benchmark
+	result := Run(context.Background(), bencher, b, Options{Iter: 100, Threads: 5, Duration: time.Hour})
+
+	bencher.AssertNumberOfCalls(t, "Exec", 7)
+	require.Equal(t, uint64(7), result.TotalExecutionCount)
+	require.Equal(t, 2, result.Threads)
+}
+
+func TestBenchmarkOptions(t *testing.T) {
+	opts := Options{Iter: 100, Threads: 25}
+
+	require.Equal(t, Options{Iter: 10, Threads: 10}, Benchmark{Iter: 10}.options(opts))
+	require.Equal(t, Options{Iter: 100, Threads: 50, Duration: time.Second}, Benchmark{Threads: 50, Duration: time.Second}.options(opts))
+	require.Equal(t, Options{Iter: 10, Threads: 25, Duration: time.Second}, Benchmark{Iter: 10, Duration: time.Second}.options(opts))
+	require.Equal(t, Options{Iter: 10, Threads: 10}, Benchmark{Iter: 10}.options(Options{Iter: 100, Threads: 25, Duration: time.Minute}))
+}
+
+func TestExec(t *testing.T) {
+	bencher := &mockedBencher{}
+	bencher.On("Exec", "CREATE TABLE t1;").Return(nil)
+
+	require.NoError(t, Exec(context.Background(), bencher, "CREATE TABLE t{{.Iter}};", "setup 1", Options{}))
+	bencher.AssertNumberOfCalls(t, "Exec", 1)
+
+	require.Error(t, Exec(context.Background(), bencher, "CREATE TABLE {{.Iter", "setup 2", Options{}))
+	bencher.AssertNumberOfCalls(t, "Exec", 1)
+}
+
+func TestRenderSeed(t *testing.T) {
+	stmt := "INSERT INTO t VALUES ({{call .RandInt64N 1000000}}, '{{call .UUIDv4}}');"
+
+	first, err := Render(stmt, "setup 1", Options{Seed: 42})
+	require.NoError(t, err)
+	again, err := Render(stmt, "setup 1", Options{Seed: 42})
+	require.NoError(t, err)
+	require.Equal(t, first, again)
+
+	other, err := Render(stmt, "setup 2", Options{Seed: 42})
+	require.NoError(t, err)
+	require.NotEqual(t, first, other)
+	other, err = Render(stmt, "setup 1", Options{Seed: 43})
+	require.NoError(t, err)
+	require.NotEqual(t, first, other)
+}
+
+func TestRunStatements(t *testing.T) {
+	bencher := &mockedBencher{}
+	bencher.On("Exec", "BEGIN").Return(nil)
+	bencher.On("Exec", "INSERT 1").Return(nil)
+	bencher.On("Exec", "INSERT 2").Return(errors.New("duplicate key"))
+	bencher.On("Exec", "COMMIT").Return(nil)
+
+	b := Benchmark{Name: "stmts", Type: TypeLoop, Stmt: "BEGIN;\nINSERT {{.Iter}};\nCOMMIT;"}
+	r := Run(context.Background(), bencher, b, Options{Iter: 2, Threads: 1})
+
+	// the second iteration stops at the failing statement
+	bencher.AssertNumberOfCalls(t, "Exec", 5)
+	require.Equal(t, uint64(2), r.TotalExecutionCount)
+	require.Equal(t, uint64(1), r.ErrorCount)
+	require.Equal(t, map[string]uint64{"duplicate key": 1}, r.Errors)
+
+	// custom delimiters
+	bencher = &mockedBencher{}
+	bencher.On("Exec", "CALL p(1)").Return(nil)
+	bencher.On("Exec", "SELECT 1; SELECT 2").Return(nil)
+	b = Benchmark{Name: "delim", Type: TypeOnce, Stmt: "CALL p({{.Iter}}) //\nSELECT 1; SELECT 2//", Delimiter: "//"}
+	Run(context.Background(), bencher, b, Options{Iter: 1, Threads: 1})
+	bencher.AssertExpectations(t)
+}
+
+func TestRunBreakdown(t *testing.T) {
+	bencher := &mockedBencher{}
+	bencher.On("Exec", "BEGIN").Return(nil)
+	bencher.On("Exec", "INSERT 1").Return(nil)
+	bencher.On("Exec", "INSERT 2").Return(errors.New("duplicate key"))
+	bencher.On("Exec", "COMMIT").Return(nil)
+
+	b := Benchmark{Name: "tx", Type: TypeLoop, Breakdown: true, Stmt: "BEGIN;\nINSERT {{.Iter}};\nCOMMIT;"}
+	r := Run(context.Background(), bencher, b, Options{Iter: 2, Threads: 1})
+
+	require.Len(t, r.Statements, 3)
+	require.Equal(t, uint64(2), r.Statements[0].SuccessCount)
+	require.Equal(t, uint64(1), r.Statements[1].SuccessCount)
+	require.Equal(t, uint64(1), r.Statements[1].ErrorCount)
+	// the failed iteration didn't execute the commit
+	require.Equal(t, uint64(1), r.Statements[2].TotalExecutionCount)
+	for _, s := range r.Statements {
+		require.Equal(t, r.Duration, s.Duration)
+	}
+
+	// statements of mixed benchmarks are recorded in the mixed results
+	b = Benchmark{Name: "mix", Type: TypeLoop, Breakdown: true, Mix: []MixStmt{{Name: "tx", Weight: 1, Stmt: "BEGIN; COMMIT;"}}}
+	r = Run(context.Background(), bencher, b, Options{Iter: 3, Threads: 1})
+	require.Empty(t, r.Statements)
+	require.Len(t, r.Mix[0].Statements, 2)
+	require.Equal(t, uint64(3), r.Mix[0].Statements[1].SuccessCount)
+
+	// without breakdown, only the iterations are recorded
+	r = Run(context.Background(), bencher, Benchmark{Type: TypeLoop, Stmt: "BEGIN; COMMIT;"}, Options{Iter: 1, Threads: 1})
+	require.Empty(t, r.Statements)
+}
+
+func TestRunStatementsSession(t *testing.T) {
+	bencher := &sessionBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+
+	b := Benchmark{Name: "session", Type: TypeLoop, Stmt: "BEGIN; INSERT {{.Iter}}; COMMIT;"}
+	Run(context.Background(), bencher, b, Options{Iter: 3, Threads: 2})
+
+	bencher.AssertNumberOfCalls(t, "Exec", 9)
+	for _, call := range bencher.Calls {
+		require.True(t, strings.HasPrefix(call.Arguments.String(0), "session:"))
+	}
+	require.Zero(t, bencher.open.Load())
+
+	// single statements don't need a session
+	bencher = &sessionBencher{}
+	bencher.On("Exec", "INSERT 1").Return(nil)
+	Run(context.Background(), bencher, Benchmark{Type: TypeOnce, Stmt: "INSERT {{.Iter}};"}, Options{Iter: 1, Threads: 1})
+	bencher.AssertExpectations(t)
+}
+
+func TestRunStatementsTimeout(t *testing.T) {
+	bencher := &blockingBencher{}
+
+	// the iteration stops at the statement which timed out
+	b := Benchmark{Name: "timeout", Type: TypeLoop, Stmt: "SELECT 1; SELECT 2;"}
+	r := Run(context.Background(), bencher, b, Options{Iter: 1, Threads: 1, StmtTimeout: time.Millisecond})
+
+	require.Equal(t, map[string]uint64{ErrStmtTimeout.Error(): 1}, r.Errors)
+}
+
+func TestRunMix(t *testing.T) {
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+
+	b := Benchmark{Name: "mix", Type: TypeLoop, Mix: []MixStmt{
+		{Name: "select", Weight: 3, Stmt: "SELECT {{.Iter}}"},
+		{Name: "update", Weight: 1, Stmt: "UPDATE {{.Iter}}"},
+	}}
+
+	result := Run(context.Background(), bencher, b, Options{Iter: 1000, Threads: 4})
+
+	require.Equal(t, uint64(1000), result.TotalExecutionCount)
+	require.Len(t, result.Mix, 2)
+	require.Equal(t, result.TotalExecutionCount, result.Mix[0].TotalExecutionCount+result.Mix[1].TotalExecutionCount)
+	// 750 expected selects, allow for randomness
+	require.InDelta(t, 750, result.Mix[0].TotalExecutionCount, 100)
+	require.Equal(t, result.Duration, result.Mix[0].Duration)
+
+	selects := 0
+	for _, call := range bencher.Calls {
+		if strings.HasPrefix(call.Arguments.String(0), "SELECT") {
+			selects++
+		}
+	}
+	require.Equal(t, int(result.Mix[0].TotalExecutionCount), selects)
+}
+
+func TestRunVars(t *testing.T) {
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+
+	b := Benchmark{Name: "vars", Type: TypeLoop, Stmt: "{{.Vars.id}} {{.Vars.id}} {{.Vars.next}}", Vars: []Var{
+		{Name: "id", Expr: "random(1, 1000000)"},
+		{Name: "next", Expr: ":id + 1"},
+	}}
+
+	Run(context.Background(), bencher, b, Options{Iter: 10, Threads: 2})
+
+	bencher.AssertNumberOfCalls(t, "Exec", 10)
+	for _, call := range bencher.Calls {
+		fields := strings.Fields(call.Arguments.String(0))
+		require.Equal(t, fields[0], fields[1])
+
+		id, err := strconv.Atoi(fields[0])
+		require.NoError(t, err)
+		require.Equal(t, strconv.Itoa(id+1), fields[2])
+	}
+}
+
+func TestRunBuildErrors(t *testing.T) {
+	testCases := []struct {
+		description string
+		givenType   BenchType
+		given       Benchmark
+	}{
+		{
+			description: "variable",
+			givenType:   TypeLoop,
+			given:       Benchmark{Stmt: "SELECT {{.Vars.x}}", Vars: []Var{{Name: "x", Expr: "1 / 0"}}},
+		},
+		{
+			description: "template",
+			givenType:   TypeLoop,
+			given:       Benchmark{Stmt: `SELECT '{{call .RandTime "2024-12-31" "2024-01-01"}}'`},
+		},
+		{
+			description: "once",
+			givenType:   TypeOnce,
+			given:       Benchmark{Stmt: "SELECT {{.Vars.x}}", Vars: []Var{{Name: "x", Expr: "1 / 0"}}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.description, func(t *testing.T) {
+			bencher := &mockedBencher{}
+			b := tt.given
+			b.Name, b.Type = "fail", tt.givenType
+
+			// the failed iterations are recorded, the run continues
+			result := Run(context.Background(), bencher, b, Options{Iter: 3, Threads: 1})
+
+			bencher.AssertNotCalled(t, "Exec", mock.Anything)
+			require.Equal(t, result.TotalExecutionCount, result.ErrorCount)
+			require.NotZero(t, result.ErrorCount)
+		})
+	}
+}
+
+func TestRunThinkTime(t *testing.T) {
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+
+	b := Benchmark{Name: "think", Type: TypeLoop, Stmt: "SELECT 1", ThinkTime: 10 * time.Millisecond}
+
+	start := time.Now()
+	result := Run(context.Background(), bencher, b, Options{Iter: 4, Threads: 2})
+
+	bencher.AssertNumberOfCalls(t, "Exec", 4)
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	// the pause isn't measured
+	require.Less(t, result.Max, 10*time.Millisecond)
+}
+
+func TestDryRun(t *testing.T) {
+	b := Benchmark{Name: "dry", Type: TypeLoop, Stmt: "SELECT {{.Iter}};"}
+
+	// the warmup iterations are assigned to the threads as well
+	buf := &strings.Builder{}
+	require.NoError(t, DryRun(buf, b, Options{Iter: 3, Threads: 2, WarmupIter: 5}, 10))
+	require.Equal(t, "-- dry: iteration 6, thread 2\nSELECT 6;\n"+
+		"-- dry: iteration 7, thread 1\nSELECT 7;\n"+
+		"-- dry: iteration 8, thread 2\nSELECT 8;\n", buf.String())
+
+	// the run metadata
+	buf.Reset()
+	meta := Benchmark{Name: "meta", Type: TypeLoop, Stmt: "SELECT {{.Thread}}, {{.ThreadIter}}, {{.Threads}}, {{.TotalIter}}, '{{.BenchName}}', '{{.RunID}}';"}
+	require.NoError(t, DryRun(buf, meta, Options{Iter: 3, Threads: 2, WarmupIter: 1, RunID: "abc"}, 10))
+	require.Equal(t, "-- meta: iteration 2, thread 2\nSELECT 2, 1, 2, 4, 'meta', 'abc';\n"+
+		"-- meta: iteration 3, thread 1\nSELECT 1, 2, 2, 4, 'meta', 'abc';\n"+
+		"-- meta: iteration 4, thread 2\nSELECT 2, 2, 2, 4, 'meta', 'abc';\n", buf.String())
+
+	// a duration renders the given iterations
+	buf.Reset()
+	require.NoError(t, DryRun(buf, b, Options{Iter: 100, Threads: 1, Duration: time.Second}, 2))
+	require.Equal(t, "-- dry: iteration 1, thread 1\nSELECT 1;\n-- dry: iteration 2, thread 1\nSELECT 2;\n", buf.String())
+
+	// once benchmarks render a single iteration
+	buf.Reset()
+	once := Benchmark{Name: "once", Type: TypeOnce, Stmt: "SELECT {{.Iter}};"}
+	require.NoError(t, DryRun(buf, once, Options{Iter: 100, Threads: 10}, 10))
+	require.Equal(t, "-- once: iteration 1, thread 1\nSELECT 1;\n", buf.String())
+
+	// mixed statements are named
+	buf.Reset()
+	mix := Benchmark{Name: "mix", Type: TypeLoop, Mix: []MixStmt{{Name: "read", Weight: 1, Stmt: "SELECT 1;"}}}
+	require.NoError(t, DryRun(buf, mix, Options{Iter: 1, Threads: 1}, 10))
+	require.Equal(t, "-- mix (read): iteration 1, thread 1\nSELECT 1;\n", buf.String())
+
+	require.Error(t, DryRun(buf, Benchmark{Stmt: "{{.Iter"}, Options{Iter: 1, Threads: 1}, 1))
+
+	// templates failing at runtime return an error instead of exiting
+	buf.Reset()
+	failing := Benchmark{Name: "failing", Type: TypeLoop, Stmt: "SELECT {{.Vars.id}};", Vars: []Var{{Name: "id", Expr: "1 / 0"}}}
+	require.ErrorContains(t, DryRun(buf, failing, Options{Iter: 3, Threads: 1}, 10), "iteration 1: failed to evaluate \\set id")
+	require.Empty(t, buf.String())
+
+	err := DryRun(buf, Benchmark{Name: "func", Type: TypeLoop, Stmt: "SELECT {{call .RandIntN 0}};"}, Options{Iter: 1, Threads: 1}, 10)
+	require.ErrorContains(t, err, "iteration 1: failed to execute template")
+	require.Empty(t, buf.String())
+}
+
+func TestRunSeed(t *testing.T) {
+	b := Benchmark{
+		Name: "seed", Type: TypeLoop,
+		Stmt: "{{.Vars.id}} {{call .RandInt64N 1000000}} {{call .UUIDv4}}",
+		Vars: []Var{{Name: "id", Expr: "random(1, 1000000)"}},
+	}
+
+	// statements executes the benchmark and returns the executed statements
+	statements := func(b Benchmark, opts Options) []string {
+		bencher := &mockedBencher{}
+		bencher.On("Exec", mock.Anything).Return(nil)
+		Run(context.Background(), bencher, b, opts)
+
+		stmts := []string{}
+		for _, call := range bencher.Calls {
+			stmts = append(stmts, call.Arguments.String(0))
+		}
+		return stmts
+	}
+
+	opts := Options{Iter: 20, Threads: 1, WarmupIter: 5, Seed: 42}
+	first := statements(b, opts)
+	require.Len(t, first, 25)
+	require.Equal(t, first, statements(b, opts))
+
+	opts.Seed = 43
+	require.NotEqual(t, first, statements(b, opts))
+
+	// other benchmarks of the run, e.g. parallel ones, draw different values
+	opts.Seed = 42
+	other := b
+	other.Name = "other"
+	require.NotEqual(t, first, statements(other, opts))
+	other.Type = TypeOnce
+	require.NotEqual(t, first[:1], statements(other, opts))
+
+	// the dry-run renders the same statements as the run
+	buf := &strings.Builder{}
+	opts.Seed = 42
+	require.NoError(t, DryRun(buf, b, opts, 20))
+	for i, stmt := range first[5:] {
+		require.Contains(t, buf.String(), fmt.Sprintf("-- seed: iteration %v, thread 1\n%v;\n", i+6, stmt))
+	}
+}
+
+func TestRunThreads(t *testing.T) {
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+
+	b := Benchmark{Name: "threads", Type: TypeLoop, Stmt: "{{.Thread}} {{.ThreadIter}} {{.Threads}} {{.TotalIter}} {{.BenchName}} {{.RunID}}"}
+	Run(context.Background(), bencher, b, Options{Iter: 10, Threads: 3, WarmupIter: 2, RunID: "run"})
+
+	bencher.AssertNumberOfCalls(t, "Exec", 12)
+	// the iterations of each thread are numbered without gaps
+	threadIters := map[string][]int{}
+	for _, call := range bencher.Calls {
+		fields := strings.Fields(call.Arguments.String(0))
+		require.Equal(t, []string{"3", "12", "threads", "run"}, fields[2:])
+
+		iter, err := strconv.Atoi(fields[1])
+		require.NoError(t, err)
+		threadIters[fields[0]] = append(threadIters[fields[0]], iter)
+	}
+	for thread, iters := range threadIters {
+		require.Contains(t, []string{"1", "2", "3"}, thread)
+		slices.Sort(iters)
+		for i, iter := range iters {
+			require.Equal(t, i+1, iter)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	a := Benchmark{Name: "a"}
+	b := Benchmark{Name: "b", Parallel: true}
+	c := Benchmark{Name: "c", Parallel: true}
+	d := Benchmark{Name: "d"}
+	e := Benchmark{Name: "e", Parallel: true}
+
+	got := Group([]Benchmark{a, b, c, d, e})
+
+	assert.Equal(t, [][]Benchmark{{a}, {b, c}, {d}, {e}}, got)
+}
+
+// concurrencyBencher tracks the max. number of concurrently executed statements.
+type concurrencyBencher struct {
+	mockedBencher
+	running atomic.Int64
+	max     atomic.Int64
+}
+
+func (b *concurrencyBencher) Exec(context.Context, string) error {
+	n := b.running.Add(1)
+	defer b.running.Add(-1)
+
+	for {
+		m := b.max.Load()
+		if n <= m || b.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func TestRunParallel(t *testing.T) {
+	// arrange
+	bencher := &concurrencyBencher{}
+	benchmarks := []Benchmark{
+		{Name: "a", Type: TypeOnce, Parallel: true, Stmt: "a"},
+		{Name: "b", Type: TypeLoop, Parallel: true, Stmt: "b"},
+		{Name: "c", Type: TypeOnce, Parallel: true, Stmt: "c"},
+	}
+
+	// act
+	results := RunParallel(context.Background(), bencher, benchmarks, Options{Iter: 3, Threads: 1})
+
+	// assert
+	require.Len(t, results, 3)
+	assert.Equal(t, uint64(1), results[0].SuccessCount)
+	assert.Equal(t, uint64(3), results[1].SuccessCount)
+	assert.Equal(t, uint64(1), results[2].SuccessCount)
+	assert.Equal(t, int64(3), bencher.max.Load())
+}
+
 func TestLoop(t *testing.T) {
 	// arrange
 	bencher := &mockedBencher{}
-	bencher.On("Exec", mock.Anything)
+	bencher.On("Exec", mock.Anything).Return(nil)
 	tmpl := template.Must(template.New("test").Parse("{{.Iter}} {{call .RandInt64}}"))
 
-	executor := bencherExecutor{
-		result: Result{
-			Start: time.Now(),
-		},
-	}
+	executor := bencherExecutor{result: newResult()}
 
 	// act
-	executor.loop(bencher, tmpl, 17, 5)
+	executor.loop(context.Background(), bencher, single(tmpl), Options{Iter: 17, Threads: 5})
 
 	// assert
 	bencher.AssertNumberOfCalls(t, "Exec", 17)
 }
 
+func TestLoopDuration(t *testing.T) {
+	// arrange
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+	tmpl := template.Must(template.New("test").Parse("{{.Iter}}"))
+
+	executor := bencherExecutor{result: newResult()}
+
+	// act
+	executor.loop(context.Background(), bencher, single(tmpl), Options{Threads: 5, Duration: 20 * time.Millisecond})
+
+	// assert
+	require.NotEmpty(t, bencher.Calls)
+
+	// every iteration was executed exactly once, without gaps
+	seen := map[string]bool{}
+	for _, c := range bencher.Calls {
+		stmt := c.Arguments.String(0)
+		require.False(t, seen[stmt], "iteration %v executed twice", stmt)
+		seen[stmt] = true
+	}
+	for i := 1; i <= len(bencher.Calls); i++ {
+		require.True(t, seen[strconv.Itoa(i)], "iteration %v missing", i)
+	}
+	// statements still running at the deadline are not recorded
+	assert.LessOrEqual(t, executor.result.TotalExecutionCount, uint64(len(bencher.Calls)))
+	assert.GreaterOrEqual(t, executor.result.TotalExecutionCount, uint64(len(bencher.Calls)-5))
+}
+
+func TestLoopRate(t *testing.T) {
+	// arrange
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+	tmpl := template.Must(template.New("test").Parse("{{.Iter}}"))
+
+	executor := bencherExecutor{result: newResult()}
+
+	// act
+	start := time.Now()
+	executor.loop(context.Background(), bencher, single(tmpl), Options{Iter: 20, Threads: 4, Rate: 1000})
+
+	// assert
+	bencher.AssertNumberOfCalls(t, "Exec", 20)
+	// the last statement is scheduled 19ms after the start
+	assert.GreaterOrEqual(t, time.Since(start), 19*time.Millisecond)
+}
+
+func TestLoopRateCoordinatedOmission(t *testing.T) {
+	// arrange
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil).Run(func(mock.Arguments) { time.Sleep(5 * time.Millisecond) })
+	tmpl := template.Must(template.New("test").Parse("{{.Iter}}"))
+
+	executor := bencherExecutor{result: newResult()}
+
+	// act
+	// one routine can't keep up with one statement per millisecond
+	executor.loop(context.Background(), bencher, single(tmpl), Options{Iter: 10, Threads: 1, Rate: 1000})
+
+	// assert
+	// the last statement was scheduled at 9ms, but only started after 45ms
+	assert.GreaterOrEqual(t, executor.result.Max, 40*time.Millisecond)
+}
+
 func TestOnce(t *testing.T) {
 	// arrange
 	bencher := &mockedBencher{}
-	bencher.On("Exec", mock.Anything)
+	bencher.On("Exec", mock.Anything).Return(nil)
 	tmpl := template.Must(template.New("test").Parse("{{.Iter}} {{call .RandInt64}}"))
 
-	executor := bencherExecutor{
-		result: Result{
-			Start: time.Now(),
-		},
-	}
+	executor := bencherExecutor{result: newResult()}
 
 	// act
-	executor.once(bencher, tmpl)
+	executor.once(context.Background(), bencher, single(tmpl), Options{})
 
 	// assert
 	bencher.AssertNumberOfCalls(t, "Exec", 1)
@@ -112,19 +655,135 @@ func TestOnce(t *testing.T) {
 func TestResults(t *testing.T) {
 	// arrange
 	bencher := &mockedBencher{}
-	bencher.On("Exec", mock.Anything)
+	bencher.On("Exec", mock.Anything).Return(nil)
 	tmpl := template.Must(template.New("test").Parse("{{.Iter}} {{call .RandInt64}}"))
 
-	executor := bencherExecutor{
-		result: Result{
-			Start: time.Now(),
-		},
-	}
+	executor := bencherExecutor{result: newResult()}
 
 	// act
-	executor.once(bencher, tmpl)
+	executor.once(context.Background(), bencher, single(tmpl), Options{})
 
 	assert.Equal(t, uint64(1), executor.result.TotalExecutionCount)
 
 	assert.Equal(t, executor.result.TotalExecutionTime, executor.result.Avg())
 }
+
+func TestResultsErrors(t *testing.T) {
+	// arrange
+	bencher := &mockedBencher{}
+	bencher.On("Exec", "2").Return(errors.New("duplicate key '2'"))
+	bencher.On("Exec", "4").Return(errors.New("duplicate key '4'"))
+	bencher.On("Exec", "5").Return(errors.New("timeout"))
+	bencher.On("Exec", mock.Anything).Return(nil)
+	tmpl := template.Must(template.New("test").Parse("{{.Iter}}"))
+
+	executor := bencherExecutor{result: newResult()}
+
+	// act
+	executor.loop(context.Background(), bencher, single(tmpl), Options{Iter: 10, Threads: 3})
+
+	// assert
+	r := executor.result
+	assert.Equal(t, uint64(10), r.TotalExecutionCount)
+	assert.Equal(t, uint64(7), r.SuccessCount)
+	assert.Equal(t, uint64(3), r.ErrorCount)
+	assert.Equal(t, map[string]uint64{"duplicate key '?'": 2, "timeout": 1}, r.Errors)
+	assert.Equal(t, int64(7), r.histogram.TotalCount())
+}
+
+func TestLoopWarmup(t *testing.T) {
+	// arrange
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+	tmpl := template.Must(template.New("test").Parse("{{.Iter}}"))
+
+	executor := bencherExecutor{result: newResult()}
+
+	// act
+	opts := Options{Iter: 10, Threads: 3, WarmupIter: 5}
+	executor.warmup(context.Background(), bencher, single(tmpl), opts)
+	executor.loop(context.Background(), bencher, single(tmpl), opts)
+
+	// assert
+	bencher.AssertNumberOfCalls(t, "Exec", 15)
+	// the loop continues with the iterations after the warmup
+	for i := 1; i <= 15; i++ {
+		bencher.AssertCalled(t, "Exec", strconv.Itoa(i))
+	}
+	assert.Equal(t, uint64(10), executor.result.TotalExecutionCount)
+	assert.Equal(t, int64(10), executor.result.histogram.TotalCount())
+}
+
+func TestRunWarmupDuration(t *testing.T) {
+	// arrange
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+
+	// act
+	start := time.Now()
+	r := Run(context.Background(), bencher, Benchmark{Type: TypeLoop, Stmt: "{{.Iter}}"}, Options{Iter: 10, Threads: 2, WarmupDuration: 10 * time.Millisecond})
+
+	// assert
+	assert.Equal(t, uint64(10), r.TotalExecutionCount)
+	assert.Greater(t, len(bencher.Calls), 10)
+	// the warmup is not part of the measured duration
+	assert.GreaterOrEqual(t, r.Start.Sub(start), 10*time.Millisecond)
+}
+
+func TestLoopStmtTimeout(t *testing.T) {
+	// arrange
+	bencher := &blockingBencher{}
+	tmpl := template.Must(template.New("test").Parse("{{.Iter}}"))
+
+	executor := bencherExecutor{result: newResult()}
+
+	// act
+	executor.loop(context.Background(), bencher, single(tmpl), Options{Iter: 3, Threads: 1, StmtTimeout: time.Millisecond})
+
+	// assert
+	r := executor.result
+	assert.Equal(t, uint64(3), r.ErrorCount)
+	assert.Equal(t, map[string]uint64{ErrStmtTimeout.Error(): 3}, r.Errors)
+}
+
+func TestLoopCancel(t *testing.T) {
+	// arrange
+	bencher := &blockingBencher{}
+	tmpl := template.Must(template.New("test").Parse("{{.Iter}}"))
+
+	executor := bencherExecutor{result: newResult()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// act
+	executor.loop(ctx, bencher, single(tmpl), Options{Threads: 5, Duration: time.Hour})
+
+	// assert
+	// the aborted statements are not recorded
+	assert.Equal(t, uint64(0), executor.result.TotalExecutionCount)
+}
+
+func TestPercentiles(t *testing.T) {
+	// arrange
+	executor := bencherExecutor{result: newResult()}
+
+	// act
+	for i := 1; i <= 100; i++ {
+		executor.result.histogram.RecordValue(int64(i * int(time.Millisecond)))
+	}
+
+	// assert
+	r := executor.result
+	assert.InEpsilon(t, 50*time.Millisecond, r.Percentile(50), 0.01)
+	assert.InEpsilon(t, 90*time.Millisecond, r.Percentile(90), 0.01)
+	assert.InEpsilon(t, 99*time.Millisecond, r.Percentile(99), 0.01)
+	assert.InEpsilon(t, 100*time.Millisecond, r.Percentile(99.9), 0.01)
+	assert.InEpsilon(t, 28866*time.Microsecond, r.StdDev(), 0.01)
+}
+
+func TestPercentilesEmpty(t *testing.T) {
+	r := Result{}
+	assert.Equal(t, time.Duration(0), r.Percentile(99))
+	assert.Equal(t, time.Duration(0), r.StdDev())
+}
@@ -0,0 +1,66 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errArgsNotInBenchmark is raised for bind parameters of setup and teardown statements.
+var errArgsNotInBenchmark = errors.New("bind parameters are only supported in benchmarks")
+
+// argMarkerRegexp matches the markers of the bind parameters in the rendered template.
+var argMarkerRegexp = regexp.MustCompile("\x00arg(\\d+)\x00")
+
+// argMarker marks the position of the ith argument in the rendered template. The
+// template is split into statements afterwards, the placeholders are numbered
+// within each statement.
+func argMarker(i int) string {
+	return "\x00arg" + strconv.Itoa(i) + "\x00"
+}
+
+// query is a rendered statement with the arguments of its bind parameters.
+type query struct {
+	stmt string
+	args []any
+}
+
+// questionPlaceholder is the placeholder when no Options.Placeholder is set.
+func questionPlaceholder(int) string {
+	return "?"
+}
+
+// bindArgs replaces the argument markers of the statements with the placeholders
+// of the database and assigns the arguments to their statements.
+func bindArgs(stmts []string, args []any, placeholder func(n int) string) []query {
+	if placeholder == nil {
+		placeholder = questionPlaceholder
+	}
+
+	queries := make([]query, 0, len(stmts))
+	for _, stmt := range stmts {
+		q := query{}
+		q.stmt = argMarkerRegexp.ReplaceAllStringFunc(stmt, func(marker string) string {
+			i, _ := strconv.Atoi(argMarkerRegexp.FindStringSubmatch(marker)[1])
+			q.args = append(q.args, args[i])
+			return placeholder(len(q.args))
+		})
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+// formatArgs formats the arguments for the dry-run, strings are quoted.
+func formatArgs(args []any) string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(string); ok {
+			formatted[i] = strconv.Quote(s)
+			continue
+		}
+		formatted[i] = fmt.Sprint(arg)
+	}
+	return strings.Join(formatted, ", ")
+}
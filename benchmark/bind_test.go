@@ -0,0 +1,98 @@
+package benchmark
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// argBencher executes statements with bind parameters.
+type argBencher struct{ mockedBencher }
+
+func (b *argBencher) ExecArgs(_ context.Context, s string, args []any) error {
+	return b.Called(s, args).Error(0)
+}
+
+func TestBindArgs(t *testing.T) {
+	dollar := func(n int) string { return "$" + strconv.Itoa(n) }
+
+	testCases := []struct {
+		description string
+		stmts       []string
+		args        []any
+		placeholder func(int) string
+		want        []query
+	}{
+		{
+			description: "no args",
+			stmts:       []string{"SELECT 1"},
+			want:        []query{{stmt: "SELECT 1"}},
+		},
+		{
+			description: "question marks",
+			stmts:       []string{"SELECT " + argMarker(0) + ", " + argMarker(1)},
+			args:        []any{int64(1), "a"},
+			want:        []query{{stmt: "SELECT ?, ?", args: []any{int64(1), "a"}}},
+		},
+		{
+			description: "numbered within each statement",
+			stmts:       []string{"SELECT " + argMarker(0), "SELECT 2", "SELECT " + argMarker(1) + ", " + argMarker(2)},
+			args:        []any{int64(1), 2.5, true},
+			placeholder: dollar,
+			want: []query{
+				{stmt: "SELECT $1", args: []any{int64(1)}},
+				{stmt: "SELECT 2"},
+				{stmt: "SELECT $1, $2", args: []any{2.5, true}},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.description, func(t *testing.T) {
+			require.Equal(t, tt.want, bindArgs(tt.stmts, tt.args, tt.placeholder))
+		})
+	}
+}
+
+func TestRunArgs(t *testing.T) {
+	bencher := &argBencher{}
+	bencher.On("ExecArgs", mock.Anything, mock.Anything).Return(nil)
+
+	b := Benchmark{
+		Name: "args", Type: TypeLoop,
+		Stmt: `INSERT INTO t VALUES ({{call .Arg .Iter}}, {{call .Arg "it's"}}); SELECT * FROM t WHERE id = {{call .Arg .Iter}};`,
+	}
+	Run(context.Background(), bencher, b, Options{Iter: 1, Threads: 1, Placeholder: func(n int) string { return "@p" + strconv.Itoa(n) }})
+
+	bencher.AssertNumberOfCalls(t, "ExecArgs", 2)
+	bencher.AssertCalled(t, "ExecArgs", "INSERT INTO t VALUES (@p1, @p2)", []any{int64(1), "it's"})
+	bencher.AssertCalled(t, "ExecArgs", "SELECT * FROM t WHERE id = @p1", []any{int64(1)})
+}
+
+func TestRunArgsNotSupported(t *testing.T) {
+	bencher := &mockedBencher{}
+
+	b := Benchmark{Name: "args", Type: TypeLoop, Stmt: "SELECT {{call .Arg .Iter}}"}
+	result := Run(context.Background(), bencher, b, Options{Iter: 2, Threads: 1})
+
+	bencher.AssertNotCalled(t, "Exec", mock.Anything)
+	require.Equal(t, uint64(2), result.ErrorCount)
+	require.Equal(t, map[string]uint64{ErrArgsNotSupported.Error(): 2}, result.Errors)
+}
+
+func TestDryRunArgs(t *testing.T) {
+	b := Benchmark{Name: "dry", Type: TypeLoop, Stmt: `SELECT {{call .Arg .Iter}}, {{call .Arg "a"}}; SELECT 1;`}
+
+	buf := &strings.Builder{}
+	require.NoError(t, DryRun(buf, b, Options{Iter: 1, Threads: 1}, 10))
+	require.Equal(t, "-- dry: iteration 1, thread 1\nSELECT ?, ?;\n-- args: 1, \"a\"\nSELECT 1;\n", buf.String())
+}
+
+func TestRenderArgs(t *testing.T) {
+	_, err := Render("SELECT {{call .Arg 1}}", "setup 1", Options{})
+	require.EqualError(t, err, "bind parameters are only supported in benchmarks")
+}
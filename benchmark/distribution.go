@@ -0,0 +1,219 @@
+package benchmark
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+// maxZipfianTheta is the largest supported skew, like in pgbench.
+const maxZipfianTheta = 1000
+
+const (
+	// zetaStep is the distance of the checkpoints of a zetaTable.
+	zetaStep = 4096
+	// maxZetaValues limits the cached zeta values of each skew.
+	maxZetaValues = 1024
+	// maxZetaTables limits the cached skews.
+	maxZetaTables = 64
+)
+
+// zetaTable computes the zeta values of a skew incrementally like the
+// ZipfianGenerator of YCSB: a larger range continues the sum of the largest
+// range so far. Smaller ranges start at the nearest checkpoint below.
+type zetaTable struct {
+	theta       float64
+	mux         sync.Mutex
+	last        int64             // the largest range computed so far
+	sum         float64           // zeta(last)
+	checkpoints []float64         // zeta(i*zetaStep)
+	values      map[int64]float64 // the recently computed values
+}
+
+// zetaTables contains the zeta tables of the skews used so far.
+var zetaTables = struct {
+	sync.Mutex
+	m map[float64]*zetaTable
+}{m: map[float64]*zetaTable{}}
+
+// zeta returns the sum of 1/i^theta for i in [1, n]. The sums are
+// extended incrementally, e.g. for a growing range of RandLatest.
+func zeta(n int64, theta float64) float64 {
+	zetaTables.Lock()
+	z, ok := zetaTables.m[theta]
+	if !ok {
+		if len(zetaTables.m) >= maxZetaTables {
+			clear(zetaTables.m)
+		}
+		z = &zetaTable{theta: theta, checkpoints: []float64{0}, values: map[int64]float64{}}
+		zetaTables.m[theta] = z
+	}
+	zetaTables.Unlock()
+	return z.get(n)
+}
+
+// get returns zeta(n) of the skew of the table.
+func (z *zetaTable) get(n int64) float64 {
+	z.mux.Lock()
+	defer z.mux.Unlock()
+
+	if v, ok := z.values[n]; ok {
+		return v
+	}
+
+	var v float64
+	if n >= z.last {
+		z.extend(n)
+		v = z.sum
+	} else {
+		i := n / zetaStep
+		v = z.checkpoints[i] + z.partial(i*zetaStep+1, n)
+	}
+
+	if len(z.values) >= maxZetaValues {
+		clear(z.values)
+	}
+	z.values[n] = v
+	return v
+}
+
+// extend continues the sum up to n and records the checkpoints on the way.
+func (z *zetaTable) extend(n int64) {
+	for z.last < n {
+		z.last++
+		z.sum += 1 / math.Pow(float64(z.last), z.theta)
+		if z.last%zetaStep == 0 {
+			z.checkpoints = append(z.checkpoints, z.sum)
+		}
+	}
+}
+
+// partial returns the sum of 1/i^theta for i in [from, to].
+func (z *zetaTable) partial(from, to int64) float64 {
+	sum := 0.0
+	for i := from; i <= to; i++ {
+		sum += 1 / math.Pow(float64(i), z.theta)
+	}
+	return sum
+}
+
+// checkRange returns the number of integers in [lb, ub], which
+// has to fit into an int64.
+func checkRange(name string, lb, ub int64) (int64, error) {
+	if ub < lb {
+		return 0, fmt.Errorf("%v: upper bound %v is less than lower bound %v", name, ub, lb)
+	}
+	if n := uint64(ub) - uint64(lb) + 1; n == 0 || n > math.MaxInt64 {
+		return 0, fmt.Errorf("%v: range [%v, %v] is too large", name, lb, ub)
+	}
+	return ub - lb + 1, nil
+}
+
+// zipfian returns a zipfian distributed integer in [lb, ub], lb is the
+// most frequent one. The skew theta is in (0, 1) like in YCSB (0.99 by
+// default there) or in (1, 1000] like in pgbench. Larger is more skewed.
+func zipfian(r *rand.Rand, name string, lb, ub int64, theta float64) (int64, error) {
+	n, err := checkRange(name, lb, ub)
+	if err != nil {
+		return 0, err
+	}
+	if theta <= 0 || theta == 1 || theta > maxZipfianTheta {
+		return 0, fmt.Errorf("%v: parameter %v is not in (0, 1) or (1, %v]", name, theta, maxZipfianTheta)
+	}
+	if theta > 1 {
+		return lb + zipfianRejection(r, n, theta) - 1, nil
+	}
+	return lb + zipfianGray(r, n, theta), nil
+}
+
+// zipfianGray returns a zipfian distributed integer in [0, n) for theta < 1,
+// see Gray et al., "Quickly Generating Billion-Record Synthetic Databases".
+func zipfianGray(r *rand.Rand, n int64, theta float64) int64 {
+	zetan := zeta(n, theta)
+	alpha := 1 / (1 - theta)
+	eta := (1 - math.Pow(2/float64(n), 1-theta)) / (1 - zeta(2, theta)/zetan)
+
+	u := r.Float64()
+	uz := u * zetan
+	switch {
+	case uz < 1:
+		return 0
+	case uz < 1+math.Pow(0.5, theta):
+		return min(1, n-1)
+	}
+	return min(int64(float64(n)*math.Pow(eta*u-eta+1, alpha)), n-1)
+}
+
+// zipfianRejection returns a zipfian distributed integer in [1, n] for theta > 1,
+// using the rejection method of Devroye, "Non-Uniform Random Variate Generation",
+// which is used by pgbench as well.
+func zipfianRejection(r *rand.Rand, n int64, theta float64) int64 {
+	b := math.Pow(2, theta-1)
+	for {
+		u := 1 - r.Float64() // (0, 1]
+		v := r.Float64()
+		x := math.Floor(math.Pow(u, -1/(theta-1)))
+		t := math.Pow(1+1/x, theta-1)
+		if v*x*(t-1)/(b-1) <= t/b && x <= float64(n) {
+			return int64(x)
+		}
+	}
+}
+
+// scrambledZipfian returns a zipfian distributed integer in [lb, ub] like
+// zipfian, but the frequent integers are spread over the range like in YCSB.
+func scrambledZipfian(r *rand.Rand, name string, lb, ub int64, theta float64) (int64, error) {
+	n, err := checkRange(name, lb, ub)
+	if err != nil {
+		return 0, err
+	}
+	rank, err := zipfian(r, name, 0, n-1, theta)
+	if err != nil {
+		return 0, err
+	}
+	return lb + int64(fnvHash(rank)%uint64(n)), nil
+}
+
+// fnvHash returns the FNV-1a hash of the integer.
+func fnvHash(i int64) uint64 {
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.LittleEndian, i)
+	return h.Sum64()
+}
+
+// hotspot returns an integer in [lb, ub], where the fraction hotOps of the
+// results are in the first hotKeys fraction of the range, e.g. 0.2 and 0.8
+// for 80% of the results on 20% of the integers. The integers of each of
+// the both parts are uniformly distributed.
+func hotspot(r *rand.Rand, name string, lb, ub int64, hotKeys, hotOps float64) (int64, error) {
+	n, err := checkRange(name, lb, ub)
+	if err != nil {
+		return 0, err
+	}
+	if hotKeys < 0 || hotKeys > 1 || hotOps < 0 || hotOps > 1 {
+		return 0, fmt.Errorf("%v: fractions %v and %v are not in [0, 1]", name, hotKeys, hotOps)
+	}
+
+	hot := int64(float64(n) * hotKeys)
+	if hot > 0 && (hot == n || r.Float64() < hotOps) {
+		return lb + r.Int64N(hot), nil
+	}
+	return lb + hot + r.Int64N(n-hot), nil
+}
+
+// latest returns a zipfian distributed integer in [lb, latest], the most recent
+// integer (latest) is the most frequent one, e.g. to read recently inserted rows.
+func latest(r *rand.Rand, name string, lb, latest int64, theta float64) (int64, error) {
+	n, err := checkRange(name, lb, latest)
+	if err != nil {
+		return 0, err
+	}
+	rank, err := zipfian(r, name, 0, n-1, theta)
+	if err != nil {
+		return 0, err
+	}
+	return latest - rank, nil
+}
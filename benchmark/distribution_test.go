@@ -0,0 +1,149 @@
+package benchmark
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// histogram draws n integers and counts each of them.
+func histogram(t *testing.T, n int, draw func() (int64, error)) map[int64]int {
+	t.Helper()
+	counts := map[int64]int{}
+	for range n {
+		v, err := draw()
+		require.NoError(t, err)
+		counts[v]++
+	}
+	return counts
+}
+
+// mostFrequent returns the integer which was drawn most often.
+func mostFrequent(counts map[int64]int) int64 {
+	var best int64
+	for v, c := range counts {
+		if c > counts[best] {
+			best = v
+		}
+	}
+	return best
+}
+
+func TestZipfian(t *testing.T) {
+	r := newRand(1, 1)
+	for _, theta := range []float64{0.5, 0.99, 1.5, 3} {
+		counts := histogram(t, 10000, func() (int64, error) { return zipfian(r, "test", 10, 109, theta) })
+		for v := range counts {
+			require.GreaterOrEqual(t, v, int64(10), theta)
+			require.LessOrEqual(t, v, int64(109), theta)
+		}
+		require.Equal(t, int64(10), mostFrequent(counts), theta)
+		// skewed, the first integer is drawn far more often than the last ones
+		require.Greater(t, counts[10], 5*counts[100], theta)
+	}
+
+	// a single integer
+	v, err := zipfian(r, "test", 5, 5, 0.99)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), v)
+}
+
+func TestScrambledZipfian(t *testing.T) {
+	r := newRand(1, 1)
+	counts := histogram(t, 10000, func() (int64, error) { return scrambledZipfian(r, "test", 1, 1000, 0.99) })
+	for v := range counts {
+		require.GreaterOrEqual(t, v, int64(1))
+		require.LessOrEqual(t, v, int64(1000))
+	}
+	// still skewed, but the hottest integer is not the first one
+	hottest := mostFrequent(counts)
+	require.NotEqual(t, int64(1), hottest)
+	require.Greater(t, counts[hottest], 10000/1000*5)
+}
+
+func TestHotspot(t *testing.T) {
+	r := newRand(1, 1)
+	counts := histogram(t, 10000, func() (int64, error) { return hotspot(r, "test", 1, 100, 0.2, 0.8) })
+	hot := 0
+	for v, c := range counts {
+		require.GreaterOrEqual(t, v, int64(1))
+		require.LessOrEqual(t, v, int64(100))
+		if v <= 20 {
+			hot += c
+		}
+	}
+	require.InDelta(t, 8000, hot, 300)
+
+	// all integers are hot
+	counts = histogram(t, 100, func() (int64, error) { return hotspot(r, "test", 1, 10, 1, 0) })
+	require.Len(t, counts, 10)
+}
+
+func TestLatest(t *testing.T) {
+	r := newRand(1, 1)
+	counts := histogram(t, 10000, func() (int64, error) { return latest(r, "test", 1, 500, 0.99) })
+	for v := range counts {
+		require.GreaterOrEqual(t, v, int64(1))
+		require.LessOrEqual(t, v, int64(500))
+	}
+	require.Equal(t, int64(500), mostFrequent(counts))
+}
+
+func TestLatestGrowingRange(t *testing.T) {
+	// a skew no other test uses, so the table is new
+	const theta = 0.77
+	r := newRand(1, 1)
+
+	start := time.Now()
+	for ub := int64(1); ub <= 20000; ub++ {
+		v, err := latest(r, "test", 1, ub, theta)
+		require.NoError(t, err)
+		require.LessOrEqual(t, v, ub)
+	}
+	// the sums are extended incrementally instead of computed for each range
+	require.Less(t, time.Since(start), 2*time.Second)
+
+	z := zetaTables.m[theta]
+	require.Equal(t, int64(20000), z.last)
+	require.LessOrEqual(t, len(z.values), maxZetaValues)
+
+	// smaller ranges start at a checkpoint
+	direct := z.partial(1, 10000)
+	require.InDelta(t, direct, zeta(10000, theta), 1e-9)
+	require.InDelta(t, z.partial(1, 3), zeta(3, theta), 1e-12)
+}
+
+func TestDistributionErrors(t *testing.T) {
+	r := newRand(1, 1)
+	_, err := zipfian(r, "RandZipfian", 10, 1, 0.99)
+	require.EqualError(t, err, "RandZipfian: upper bound 1 is less than lower bound 10")
+	_, err = zipfian(r, "RandZipfian", 1, 10, 1)
+	require.EqualError(t, err, "RandZipfian: parameter 1 is not in (0, 1) or (1, 1000]")
+	_, err = scrambledZipfian(r, "RandScrambledZipfian", 10, 1, 0.99)
+	require.EqualError(t, err, "RandScrambledZipfian: upper bound 1 is less than lower bound 10")
+	_, err = hotspot(r, "RandHotspot", 1, 10, 0.2, 80)
+	require.EqualError(t, err, "RandHotspot: fractions 0.2 and 80 are not in [0, 1]")
+	_, err = latest(r, "RandLatest", 1, 0, 0.99)
+	require.EqualError(t, err, "RandLatest: upper bound 0 is less than lower bound 1")
+
+	// the number of integers in the range doesn't fit into an int64
+	_, err = zipfian(r, "random_zipfian", 0, math.MaxInt64, 1.5)
+	require.EqualError(t, err, "random_zipfian: range [0, 9223372036854775807] is too large")
+	_, err = hotspot(r, "random_hotspot", 0, math.MaxInt64, 0.5, 0.5)
+	require.EqualError(t, err, "random_hotspot: range [0, 9223372036854775807] is too large")
+	_, err = scrambledZipfian(r, "random_scrambled_zipfian", math.MinInt64, math.MaxInt64, 0.99)
+	require.Error(t, err)
+	_, err = zipfian(r, "random_zipfian", 1, math.MaxInt64, 1.5)
+	require.NoError(t, err)
+}
+
+func TestRenderStmtDistributions(t *testing.T) {
+	tmpl, err := parseTemplate("test", `{{call .RandZipfian 1 1 0.99}} {{call .RandScrambledZipfian 1 1 1.1}} {{call .RandHotspot 1 1 0.2 0.8}} {{call .RandLatest 1 .Iter 0.99}}`)
+	require.NoError(t, err)
+
+	got, _, err := renderStmt(tmpl, firstIteration(newRand(1, 1)), nil)
+	require.NoError(t, err)
+	require.Equal(t, "1 1 1 1", got)
+}
@@ -0,0 +1,84 @@
+package benchmark
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DryRun writes the statements of the benchmark to w instead of executing them,
+// each one terminated by the delimiter and followed by a comment with the
+// arguments of its bind parameters, if any.
+// Loop benchmarks render at most iter iterations. All iterations, including
+// the warmup, are assigned to the threads in turn. In a real run, each thread
+// executes the next free iteration.
+func DryRun(w io.Writer, b Benchmark, opts Options, iter int) error {
+	t, err := newStatements(b)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %v", err)
+	}
+	opts = b.options(opts)
+	t.placeholder = opts.Placeholder
+
+	// the iterations of the loop continue after the warmup iterations
+	first, last, threads := 1, 1, 1
+	if b.Type == TypeLoop {
+		threads = max(opts.Threads, 1)
+		first = opts.WarmupIter + 1
+		last = opts.WarmupIter + iter
+		if opts.Duration == 0 {
+			last = min(last, opts.WarmupIter+opts.Iter)
+		}
+	}
+
+	delim := cmp.Or(b.Delimiter, defaultDelimiter)
+
+	// With the seed of the run, a single thread renders the same statements.
+	// The warmup iterations draw from the random generators as well.
+	ths := newThreads(benchSeed(opts.Seed, b.Name), threads)
+	start := time.Now()
+	for i := 1; i <= last; i++ {
+		thread := (i-1)%threads + 1
+		th := ths[thread-1]
+		th.iter++
+		stmts, mix, err := t.build(iteration{
+			iter:       i,
+			thread:     thread,
+			threadIter: th.iter,
+			threads:    threads,
+			totalIter:  totalIter(b.Type, opts),
+			benchName:  b.Name,
+			runID:      opts.RunID,
+			start:      start,
+			rand:       th.rand,
+		})
+		if err != nil {
+			return fmt.Errorf("iteration %v: %w", i, err)
+		}
+		if i < first {
+			continue
+		}
+
+		name := b.Name
+		if len(b.Mix) > 0 {
+			name = fmt.Sprintf("%v (%v)", b.Name, b.Mix[mix].Name)
+		}
+
+		if _, err := fmt.Fprintf(w, "-- %v: iteration %v, thread %v\n", name, i, thread); err != nil {
+			return err
+		}
+		for _, q := range stmts {
+			if _, err := fmt.Fprintf(w, "%v%v\n", q.stmt, delim); err != nil {
+				return err
+			}
+			if len(q.args) == 0 {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "-- args: %v\n", formatArgs(q.args)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,675 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Var is a script variable, set by '\set name expr'.
+// The expression is evaluated each iteration.
+type Var struct {
+	Name string
+	Expr string
+}
+
+// errDivByZero is returned when an expression divides by zero.
+var errDivByZero = errors.New("division by zero")
+
+// expr is a node of a parsed expression. The values are either int64 or float64.
+type expr interface {
+	eval(r *rand.Rand, vars map[string]any) (any, error)
+}
+
+type (
+	numberExpr struct{ value any }
+	varExpr    struct{ name string }
+	negExpr    struct{ x expr }
+	binaryExpr struct {
+		op   byte
+		x, y expr
+	}
+	callExpr struct {
+		fn   function
+		args []expr
+	}
+	// compareExpr and the logical expressions result in 1 (true) or 0 (false).
+	compareExpr struct {
+		op   string
+		x, y expr
+	}
+	logicExpr struct {
+		and  bool // AND, otherwise OR
+		x, y expr
+	}
+	notExpr struct{ x expr }
+)
+
+func (e numberExpr) eval(*rand.Rand, map[string]any) (any, error) {
+	return e.value, nil
+}
+
+func (e varExpr) eval(r *rand.Rand, vars map[string]any) (any, error) {
+	v, ok := vars[e.name]
+	if !ok {
+		return nil, fmt.Errorf("undefined variable :%v", e.name)
+	}
+	return v, nil
+}
+
+func (e negExpr) eval(r *rand.Rand, vars map[string]any) (any, error) {
+	x, err := e.x.eval(r, vars)
+	if err != nil {
+		return nil, err
+	}
+	if i, ok := x.(int64); ok {
+		return -i, nil
+	}
+	return -x.(float64), nil
+}
+
+func (e binaryExpr) eval(r *rand.Rand, vars map[string]any) (any, error) {
+	x, err := e.x.eval(r, vars)
+	if err != nil {
+		return nil, err
+	}
+	y, err := e.y.eval(r, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	xi, xInt := x.(int64)
+	yi, yInt := y.(int64)
+
+	// integer arithmetic when both operands are integers
+	if xInt && yInt {
+		switch e.op {
+		case '+':
+			return xi + yi, nil
+		case '-':
+			return xi - yi, nil
+		case '*':
+			return xi * yi, nil
+		case '/':
+			if yi == 0 {
+				return nil, errDivByZero
+			}
+			return xi / yi, nil
+		case '%':
+			if yi == 0 {
+				return nil, errDivByZero
+			}
+			return xi % yi, nil
+		}
+	}
+
+	xf, yf := toFloat(x), toFloat(y)
+	switch e.op {
+	case '+':
+		return xf + yf, nil
+	case '-':
+		return xf - yf, nil
+	case '*':
+		return xf * yf, nil
+	case '/':
+		if yf == 0 {
+			return nil, errDivByZero
+		}
+		return xf / yf, nil
+	}
+	return nil, fmt.Errorf("operator %c requires integers", e.op)
+}
+
+func (e compareExpr) eval(r *rand.Rand, vars map[string]any) (any, error) {
+	x, err := e.x.eval(r, vars)
+	if err != nil {
+		return nil, err
+	}
+	y, err := e.y.eval(r, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	c := 0
+	xi, xInt := x.(int64)
+	yi, yInt := y.(int64)
+	if xInt && yInt {
+		c = cmpInt(xi, yi)
+	} else {
+		xf, yf := toFloat(x), toFloat(y)
+		c = cmpInt(btoi(xf > yf), btoi(xf < yf))
+	}
+
+	switch e.op {
+	case "=":
+		return btoi(c == 0), nil
+	case "<>", "!=":
+		return btoi(c != 0), nil
+	case "<":
+		return btoi(c < 0), nil
+	case "<=":
+		return btoi(c <= 0), nil
+	case ">":
+		return btoi(c > 0), nil
+	default: // ">="
+		return btoi(c >= 0), nil
+	}
+}
+
+func (e logicExpr) eval(r *rand.Rand, vars map[string]any) (any, error) {
+	x, err := e.x.eval(r, vars)
+	if err != nil {
+		return nil, err
+	}
+	// short-circuit like pgbench
+	if isTrue(x) != e.and {
+		return btoi(!e.and), nil
+	}
+	y, err := e.y.eval(r, vars)
+	if err != nil {
+		return nil, err
+	}
+	return btoi(isTrue(y)), nil
+}
+
+func (e notExpr) eval(r *rand.Rand, vars map[string]any) (any, error) {
+	x, err := e.x.eval(r, vars)
+	if err != nil {
+		return nil, err
+	}
+	return btoi(!isTrue(x)), nil
+}
+
+func (e callExpr) eval(r *rand.Rand, vars map[string]any) (any, error) {
+	args := make([]any, 0, len(e.args))
+	for _, a := range e.args {
+		v, err := a.eval(r, vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return e.fn.call(r, args)
+}
+
+func toFloat(v any) float64 {
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+	return v.(float64)
+}
+
+// isTrue reports whether the value is non-zero.
+func isTrue(v any) bool {
+	return toFloat(v) != 0
+}
+
+// btoi converts the boolean to 1 or 0.
+func btoi(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func cmpInt(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func toInt(v any) int64 {
+	if f, ok := v.(float64); ok {
+		return int64(f)
+	}
+	return v.(int64)
+}
+
+// function is a built-in function of the expressions.
+// Variadic functions accept minArgs or more arguments.
+type function struct {
+	minArgs  int
+	variadic bool
+	call     func(r *rand.Rand, args []any) (any, error)
+}
+
+var functions = map[string]function{
+	"abs": {minArgs: 1, call: func(_ *rand.Rand, args []any) (any, error) {
+		if i, ok := args[0].(int64); ok {
+			if i < 0 {
+				return -i, nil
+			}
+			return i, nil
+		}
+		return math.Abs(args[0].(float64)), nil
+	}},
+	"int": {minArgs: 1, call: func(_ *rand.Rand, args []any) (any, error) {
+		return toInt(args[0]), nil
+	}},
+	"double": {minArgs: 1, call: func(_ *rand.Rand, args []any) (any, error) {
+		return toFloat(args[0]), nil
+	}},
+	"sqrt": {minArgs: 1, call: func(_ *rand.Rand, args []any) (any, error) {
+		return math.Sqrt(toFloat(args[0])), nil
+	}},
+	"pi": {call: func(*rand.Rand, []any) (any, error) {
+		return math.Pi, nil
+	}},
+	"greatest": {minArgs: 1, variadic: true, call: func(_ *rand.Rand, args []any) (any, error) {
+		return extreme(args, func(a, b float64) bool { return a > b }), nil
+	}},
+	"least": {minArgs: 1, variadic: true, call: func(_ *rand.Rand, args []any) (any, error) {
+		return extreme(args, func(a, b float64) bool { return a < b }), nil
+	}},
+	"random": {minArgs: 2, call: func(r *rand.Rand, args []any) (any, error) {
+		lb, ub := toInt(args[0]), toInt(args[1])
+		if ub < lb {
+			return nil, fmt.Errorf("random: upper bound %v is less than lower bound %v", ub, lb)
+		}
+		// the span of e.g. random(0, 9223372036854775807) doesn't fit into an int64
+		span := uint64(ub) - uint64(lb)
+		if span == math.MaxUint64 {
+			return int64(r.Uint64()), nil
+		}
+		return lb + int64(r.Uint64N(span+1)), nil
+	}},
+	"random_exponential": {minArgs: 3, call: func(r *rand.Rand, args []any) (any, error) {
+		lb, ub, param := toInt(args[0]), toInt(args[1]), toFloat(args[2])
+		if ub < lb {
+			return nil, fmt.Errorf("random_exponential: upper bound %v is less than lower bound %v", ub, lb)
+		}
+		if param <= 0 {
+			return nil, fmt.Errorf("random_exponential: parameter %v is not greater than 0", param)
+		}
+		cut := math.Exp(-param)
+		// uniform in (0, 1], the result is in [0, 1)
+		x := -math.Log(cut+(1-cut)*(1-r.Float64())) / param
+		return scale(lb, ub, x), nil
+	}},
+	"random_gaussian": {minArgs: 3, call: func(r *rand.Rand, args []any) (any, error) {
+		lb, ub, param := toInt(args[0]), toInt(args[1]), toFloat(args[2])
+		if ub < lb {
+			return nil, fmt.Errorf("random_gaussian: upper bound %v is less than lower bound %v", ub, lb)
+		}
+		if param < 2 {
+			return nil, fmt.Errorf("random_gaussian: parameter %v is less than 2", param)
+		}
+		// cut the normal distribution at -param and +param standard deviations
+		stddev := r.NormFloat64()
+		for stddev < -param || stddev >= param {
+			stddev = r.NormFloat64()
+		}
+		x := (stddev + param) / (param * 2)
+		return scale(lb, ub, x), nil
+	}},
+	"random_zipfian": {minArgs: 3, call: func(r *rand.Rand, args []any) (any, error) {
+		return zipfian(r, "random_zipfian", toInt(args[0]), toInt(args[1]), toFloat(args[2]))
+	}},
+	"random_scrambled_zipfian": {minArgs: 3, call: func(r *rand.Rand, args []any) (any, error) {
+		return scrambledZipfian(r, "random_scrambled_zipfian", toInt(args[0]), toInt(args[1]), toFloat(args[2]))
+	}},
+	"random_hotspot": {minArgs: 4, call: func(r *rand.Rand, args []any) (any, error) {
+		return hotspot(r, "random_hotspot", toInt(args[0]), toInt(args[1]), toFloat(args[2]), toFloat(args[3]))
+	}},
+	"random_latest": {minArgs: 3, call: func(r *rand.Rand, args []any) (any, error) {
+		return latest(r, "random_latest", toInt(args[0]), toInt(args[1]), toFloat(args[2]))
+	}},
+}
+
+// scale returns the integer at the fraction x in [0, 1) of [lb, ub], also
+// for ranges which don't fit into an int64.
+func scale(lb, ub int64, x float64) int64 {
+	span := uint64(ub) - uint64(lb)
+	offset := (float64(span) + 1) * x
+	// the float might round up to the end of the range
+	if offset >= float64(span) {
+		return ub
+	}
+	return lb + int64(uint64(offset))
+}
+
+// extreme returns the argument which is preferred by less. The result is
+// a float when one of the arguments is a float.
+func extreme(args []any, less func(a, b float64) bool) any {
+	result := args[0]
+	isFloat := false
+	for _, a := range args {
+		if _, ok := a.(float64); ok {
+			isFloat = true
+		}
+		if less(toFloat(a), toFloat(result)) {
+			result = a
+		}
+	}
+	if isFloat {
+		return toFloat(result)
+	}
+	return result
+}
+
+// exprError is a syntax error at the position (0-based) of the expression.
+type exprError struct {
+	pos int
+	msg string
+}
+
+func (e *exprError) Error() string {
+	return e.msg
+}
+
+func exprErrorf(pos int, format string, args ...any) error {
+	return &exprError{pos: pos, msg: fmt.Sprintf(format, args...)}
+}
+
+// exprParser is a recursive descent parser for pgbench-like expressions:
+//
+//	expr    = and { "OR" and }
+//	and     = not { "AND" not }
+//	not     = "NOT" not | compare
+//	compare = sum [ ("=" | "<>" | "!=" | "<" | "<=" | ">" | ">=") sum ]
+//	sum     = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/" | "%") unary }
+//	unary   = "-" unary | "+" unary | primary
+//	primary = number | "true" | "false" | ":" name | name "(" [ expr { "," expr } ] ")" | "(" expr ")"
+//
+// Keywords are case-insensitive.
+type exprParser struct {
+	s    string
+	pos  int
+	refs []string // the referenced variables
+}
+
+// parseExpr parses the expression and returns the names of the referenced variables.
+func parseExpr(s string) (expr, []string, error) {
+	p := &exprParser{s: s}
+	e, err := p.expr()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, nil, exprErrorf(p.pos, "unexpected %q at position %v", p.s[p.pos:], p.pos+1)
+	}
+	return e, p.refs, nil
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes the next character when it's one of the given characters.
+func (p *exprParser) accept(chars string) (byte, bool) {
+	p.skipSpace()
+	if p.pos < len(p.s) && strings.IndexByte(chars, p.s[p.pos]) >= 0 {
+		p.pos++
+		return p.s[p.pos-1], true
+	}
+	return 0, false
+}
+
+// acceptKeyword consumes the next word when it's the given keyword.
+func (p *exprParser) acceptKeyword(kw string) bool {
+	p.skipSpace()
+	end := p.pos + len(kw)
+	if end > len(p.s) || !strings.EqualFold(p.s[p.pos:end], kw) || end < len(p.s) && isNameChar(p.s[end], false) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+// acceptOp consumes the next operator when it's one of the given operators.
+// Longer operators have to be listed first.
+func (p *exprParser) acceptOp(ops ...string) (string, bool) {
+	p.skipSpace()
+	for _, op := range ops {
+		if strings.HasPrefix(p.s[p.pos:], op) {
+			p.pos += len(op)
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expr() (expr, error) {
+	x, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		y, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		x = logicExpr{x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *exprParser) and() (expr, error) {
+	x, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		y, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		x = logicExpr{and: true, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *exprParser) not() (expr, error) {
+	if !p.acceptKeyword("NOT") {
+		return p.compare()
+	}
+	x, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	return notExpr{x: x}, nil
+}
+
+func (p *exprParser) compare() (expr, error) {
+	x, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOp("<>", "<=", ">=", "!=", "=", "<", ">")
+	if !ok {
+		return x, nil
+	}
+	y, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	return compareExpr{op: op, x: x, y: y}, nil
+}
+
+func (p *exprParser) sum() (expr, error) {
+	x, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+-")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryExpr{op: op, x: x, y: y}
+	}
+}
+
+func (p *exprParser) term() (expr, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*/%")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryExpr{op: op, x: x, y: y}
+	}
+}
+
+func (p *exprParser) unary() (expr, error) {
+	op, ok := p.accept("+-")
+	if !ok {
+		return p.primary()
+	}
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if op == '-' {
+		return negExpr{x: x}, nil
+	}
+	return x, nil
+}
+
+func (p *exprParser) primary() (expr, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return nil, exprErrorf(p.pos, "unexpected end of expression")
+	}
+
+	switch c := p.s[p.pos]; {
+	case c == '(':
+		p.pos++
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, exprErrorf(p.pos, "missing ')' at position %v", p.pos+1)
+		}
+		return x, nil
+	case c == ':':
+		p.pos++
+		name := p.name()
+		if name == "" {
+			return nil, exprErrorf(p.pos, "missing variable name at position %v", p.pos+1)
+		}
+		p.refs = append(p.refs, name)
+		return varExpr{name: name}, nil
+	case c >= '0' && c <= '9' || c == '.':
+		return p.number()
+	case p.acceptKeyword("true"):
+		return numberExpr{value: int64(1)}, nil
+	case p.acceptKeyword("false"):
+		return numberExpr{value: int64(0)}, nil
+	case isNameChar(c, true):
+		return p.call()
+	default:
+		return nil, exprErrorf(p.pos, "unexpected %q at position %v", c, p.pos+1)
+	}
+}
+
+func (p *exprParser) number() (expr, error) {
+	start := p.pos
+	isFloat := false
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.':
+			isFloat = true
+		case (c == 'e' || c == 'E') && p.pos > start:
+			isFloat = true
+			// the exponent might have a sign
+			if p.pos+1 < len(p.s) && (p.s[p.pos+1] == '+' || p.s[p.pos+1] == '-') {
+				p.pos++
+			}
+		default:
+			return parseNumber(p.s[start:p.pos], start, isFloat)
+		}
+		p.pos++
+	}
+	return parseNumber(p.s[start:p.pos], start, isFloat)
+}
+
+// parseNumber parses the number s, which starts at the given position of the expression.
+func parseNumber(s string, start int, isFloat bool) (expr, error) {
+	if isFloat {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, exprErrorf(start, "invalid number %q", s)
+		}
+		return numberExpr{value: f}, nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, exprErrorf(start, "invalid number %q", s)
+	}
+	return numberExpr{value: i}, nil
+}
+
+func (p *exprParser) call() (expr, error) {
+	start := p.pos
+	name := p.name()
+	fn, ok := functions[name]
+	if !ok {
+		return nil, exprErrorf(start, "unknown function %v", name)
+	}
+	if _, ok := p.accept("("); !ok {
+		return nil, exprErrorf(p.pos, "missing '(' after %v", name)
+	}
+
+	args := []expr{}
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			sep, ok := p.accept(",)")
+			if !ok {
+				return nil, exprErrorf(p.pos, "missing ')' at position %v", p.pos+1)
+			}
+			if sep == ')' {
+				break
+			}
+		}
+	}
+
+	if len(args) < fn.minArgs || !fn.variadic && len(args) > fn.minArgs {
+		return nil, exprErrorf(start, "%v: wrong number of arguments: %v", name, len(args))
+	}
+	return callExpr{fn: fn, args: args}, nil
+}
+
+// name consumes an identifier.
+func (p *exprParser) name() string {
+	start := p.pos
+	for p.pos < len(p.s) && isNameChar(p.s[p.pos], p.pos == start) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
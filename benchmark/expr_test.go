@@ -0,0 +1,166 @@
+package benchmark
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalExpr(t *testing.T) {
+	vars := map[string]any{"scale": int64(10), "ratio": 0.5}
+
+	testCases := []struct {
+		in   string
+		want any
+	}{
+		{in: "42", want: int64(42)},
+		{in: "1.5", want: 1.5},
+		{in: "1e3", want: 1000.0},
+		{in: "1 + 2 * 3", want: int64(7)},
+		{in: "(1 + 2) * 3", want: int64(9)},
+		{in: "7 / 2", want: int64(3)},
+		{in: "7 / 2.0", want: 3.5},
+		{in: "7 % 4", want: int64(3)},
+		{in: "-3 + +1", want: int64(-2)},
+		{in: "100000 * :scale", want: int64(1000000)},
+		{in: ":scale * :ratio", want: 5.0},
+		{in: "abs(-3)", want: int64(3)},
+		{in: "int(3.7)", want: int64(3)},
+		{in: "double(3)", want: 3.0},
+		{in: "sqrt(16)", want: 4.0},
+		{in: "greatest(1, 5, 3)", want: int64(5)},
+		{in: "least(4, 2.5, 3)", want: 2.5},
+		{in: "random(7, 7)", want: int64(7)},
+		{in: "random_zipfian(3, 3, 1.5)", want: int64(3)},
+		{in: "random_scrambled_zipfian(3, 3, 0.99)", want: int64(3)},
+		{in: "random_hotspot(3, 3, 0.2, 0.8)", want: int64(3)},
+		{in: "random_latest(1, :scale, 0.99) <= :scale", want: int64(1)},
+		{in: "1 + 1 = 2", want: int64(1)},
+		{in: "1 <> 1", want: int64(0)},
+		{in: "1 != 2", want: int64(1)},
+		{in: ":ratio < 1", want: int64(1)},
+		{in: ":scale <= 9", want: int64(0)},
+		{in: "2.5 >= 2.5", want: int64(1)},
+		{in: ":scale > 5 AND :ratio > 1", want: int64(0)},
+		{in: ":scale > 5 and :ratio > 1 or true", want: int64(1)},
+		{in: "NOT false", want: int64(1)},
+		{in: "not :scale = 10", want: int64(0)},
+		{in: "false AND 1 / 0 = 1", want: int64(0)},
+		{in: "true OR 1 / 0 = 1", want: int64(1)},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.in, func(t *testing.T) {
+			e, _, err := parseExpr(tt.in)
+			require.NoError(t, err)
+
+			got, err := e.eval(newRand(1, 1), vars)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvalExprRandom(t *testing.T) {
+	r := newRand(1, 1)
+	for _, in := range []string{"random(1, 10)", "random_exponential(1, 10, 2.5)", "random_gaussian(1, 10, 2.5)"} {
+		e, _, err := parseExpr(in)
+		require.NoError(t, err)
+
+		for range 1000 {
+			got, err := e.eval(r, nil)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, got, int64(1), in)
+			require.LessOrEqual(t, got, int64(10), in)
+		}
+	}
+}
+
+func TestEvalExprRandomFullRange(t *testing.T) {
+	r := newRand(1, 1)
+	for _, in := range []string{
+		"random(0, 9223372036854775807)",
+		"random(-9223372036854775807 - 1, 9223372036854775807)",
+		"random_exponential(-9223372036854775807 - 1, 9223372036854775807, 2.5)",
+		"random_gaussian(0, 9223372036854775807, 2.5)",
+	} {
+		e, _, err := parseExpr(in)
+		require.NoError(t, err)
+
+		for range 1000 {
+			_, err := e.eval(r, nil)
+			require.NoError(t, err, in)
+		}
+	}
+
+	// the lower bound is kept for full width ranges
+	e, _, err := parseExpr("random(0, 9223372036854775807)")
+	require.NoError(t, err)
+	for range 1000 {
+		got, err := e.eval(r, nil)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, got, int64(0))
+	}
+}
+
+func TestEvalExprErrors(t *testing.T) {
+	for in, want := range map[string]error{
+		"1 / 0":                     errDivByZero,
+		"1 % 0":                     errDivByZero,
+		"1.5 % 2":                   errors.New("operator % requires integers"),
+		":missing":                  errors.New("undefined variable :missing"),
+		"random(10, 1)":             errors.New("random: upper bound 1 is less than lower bound 10"),
+		"random_gaussian(1, 10, 1)": errors.New("random_gaussian: parameter 1 is less than 2"),
+		"random_zipfian(1, 10, 1)":  errors.New("random_zipfian: parameter 1 is not in (0, 1) or (1, 1000]"),
+	} {
+		e, _, err := parseExpr(in)
+		require.NoError(t, err, in)
+
+		_, err = e.eval(newRand(1, 1), map[string]any{})
+		require.Equal(t, want, err, in)
+	}
+}
+
+func TestEvalVarsClientID(t *testing.T) {
+	vars, err := compileVars([]Var{{Name: "part", Expr: ":client_id * 100"}})
+	require.NoError(t, err)
+	it := firstIteration(newRand(1, 1))
+	it.thread = 3
+
+	got, err := evalVars(vars, it)
+	require.NoError(t, err)
+	// the builtin is only a value of the expressions
+	require.Equal(t, map[string]any{"part": int64(200)}, got)
+
+	vars, err = compileVars([]Var{{Name: "client_id", Expr: "7"}, {Name: "part", Expr: ":client_id"}})
+	require.NoError(t, err)
+	got, err = evalVars(vars, it)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"client_id": int64(7), "part": int64(7)}, got)
+}
+
+func TestParseExpr(t *testing.T) {
+	_, refs, err := parseExpr("random(1, :a) + :b * 2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, refs)
+
+	for in, want := range map[string]string{
+		"":            "unexpected end of expression",
+		"1 +":         "unexpected end of expression",
+		"(1 + 2":      "missing ')' at position 7",
+		"1 2":         `unexpected "2" at position 3`,
+		"foo(1)":      "unknown function foo",
+		"random(1)":   "random: wrong number of arguments: 1",
+		"abs":         "missing '(' after abs",
+		": 1":         "missing variable name at position 2",
+		"1 $ 2":       `unexpected "$ 2" at position 3`,
+		"1.2.3":       `invalid number "1.2.3"`,
+		"least()":     "least: wrong number of arguments: 0",
+		"abs(1, 2)":   "abs: wrong number of arguments: 2",
+		"random(1 2)": "missing ')' at position 10",
+	} {
+		_, _, err := parseExpr(in)
+		require.EqualError(t, err, want, in)
+	}
+}
@@ -0,0 +1,161 @@
+package benchmark
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// alphanumeric is the default alphabet of RandString.
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// timeLayout is the format of the generated timestamps, which is understood by all databases.
+const timeLayout = "2006-01-02 15:04:05"
+
+// timeLayouts are the accepted formats of the RandTime bounds.
+var timeLayouts = []string{time.RFC3339, timeLayout, "2006-01-02"}
+
+var (
+	firstNames = []string{
+		"Alice", "Amelia", "Anna", "Ben", "Carlos", "Chloe", "Daniel", "David", "Elena", "Emma",
+		"Ethan", "Fatima", "Grace", "Hannah", "Hiro", "Isabel", "Jack", "James", "Julia", "Kai",
+		"Laura", "Leon", "Liam", "Lucas", "Maria", "Mateo", "Mia", "Noah", "Olivia", "Omar",
+		"Paul", "Priya", "Rosa", "Sara", "Sofia", "Thomas", "Victor", "Wei", "Yuki", "Zoe",
+	}
+	lastNames = []string{
+		"Anderson", "Becker", "Brown", "Chen", "Clark", "Davis", "Diaz", "Fischer", "Garcia", "Hansen",
+		"Hernandez", "Ivanov", "Johnson", "Kim", "Kowalski", "Lee", "Lopez", "Martin", "Meyer", "Miller",
+		"Moore", "Nguyen", "Novak", "Patel", "Rossi", "Schmidt", "Silva", "Smith", "Suzuki", "Taylor",
+		"Thomas", "Walker", "Wang", "Weber", "White", "Williams", "Wilson", "Wright", "Young", "Zhang",
+	}
+	emailDomains = []string{"example.com", "example.net", "example.org"}
+)
+
+// randString returns n random characters of the alphabet,
+// alphanumeric characters when no alphabet is given.
+func randString(r *rand.Rand, n int64, alphabet ...string) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("RandString: negative length %v", n)
+	}
+	chars := []rune(alphanumeric)
+	if len(alphabet) > 0 {
+		chars = []rune(strings.Join(alphabet, ""))
+	}
+	if len(chars) == 0 {
+		return "", errors.New("RandString: empty alphabet")
+	}
+
+	s := make([]rune, n)
+	for i := range s {
+		s[i] = chars[r.IntN(len(chars))]
+	}
+	return string(s), nil
+}
+
+// randHex returns n random bytes, hex encoded.
+func randHex(r *rand.Rand, n int64) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("RandHex: negative length %v", n)
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.Uint32())
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// uuidV4 returns a random UUID (version 4).
+func uuidV4(r *rand.Rand) string {
+	var u [16]byte
+	for i := range u {
+		u[i] = byte(r.Uint32())
+	}
+	return formatUUID(u, 4)
+}
+
+// uuidV7 returns a time-ordered UUID (version 7), the first
+// 48 bits are the Unix timestamp in milliseconds.
+func uuidV7(r *rand.Rand) string {
+	var u [16]byte
+	ms := time.Now().UnixMilli()
+	for i := range 6 {
+		u[i] = byte(ms >> (40 - 8*i))
+	}
+	for i := 6; i < len(u); i++ {
+		u[i] = byte(r.Uint32())
+	}
+	return formatUUID(u, 7)
+}
+
+// NewRunID returns a new time-ordered ID for Options.RunID. It doesn't
+// depend on the seed, a replayed run gets a new ID.
+func NewRunID() string {
+	return uuidV7(rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+}
+
+// formatUUID sets the version and the variant (RFC 9562) and formats the UUID.
+func formatUUID(u [16]byte, version byte) string {
+	u[6] = u[6]&0x0f | version<<4
+	u[8] = u[8]&0x3f | 0x80
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// randTime returns a random timestamp in [from, to).
+func randTime(r *rand.Rand, from, to string) (string, error) {
+	start, err := parseTime(from)
+	if err != nil {
+		return "", fmt.Errorf("RandTime: %w", err)
+	}
+	end, err := parseTime(to)
+	if err != nil {
+		return "", fmt.Errorf("RandTime: %w", err)
+	}
+	if !end.After(start) {
+		return "", fmt.Errorf("RandTime: %v is not after %v", to, from)
+	}
+	return start.Add(time.Duration(r.Int64N(int64(end.Sub(start))))).UTC().Format(timeLayout), nil
+}
+
+// parseTime parses the timestamp in one of the timeLayouts.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q, neither RFC 3339, %q nor %q", s, timeLayout, "2006-01-02")
+}
+
+// randChoice returns one of the items.
+func randChoice(r *rand.Rand, items ...any) (any, error) {
+	if len(items) == 0 {
+		return nil, errors.New("RandChoice: no items")
+	}
+	return items[r.IntN(len(items))], nil
+}
+
+// randFirstName returns a common first name.
+func randFirstName(r *rand.Rand) string {
+	return firstNames[r.IntN(len(firstNames))]
+}
+
+// randLastName returns a common last name.
+func randLastName(r *rand.Rand) string {
+	return lastNames[r.IntN(len(lastNames))]
+}
+
+// randName returns a first and a last name.
+func randName(r *rand.Rand) string {
+	return randFirstName(r) + " " + randLastName(r)
+}
+
+// randEmail returns an email address like 'emma.schmidt4711@example.com'. The
+// reserved example domains never belong to a real mailbox.
+func randEmail(r *rand.Rand) string {
+	return fmt.Sprintf("%v.%v%v@%v",
+		strings.ToLower(randFirstName(r)), strings.ToLower(randLastName(r)), r.IntN(10000), emailDomains[r.IntN(len(emailDomains))])
+}
@@ -0,0 +1,104 @@
+package benchmark
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandString(t *testing.T) {
+	r := newRand(1, 1)
+	s, err := randString(r, 20)
+	require.NoError(t, err)
+	require.Regexp(t, `^[a-zA-Z0-9]{20}$`, s)
+
+	s, err = randString(r, 10, "ab", "ü")
+	require.NoError(t, err)
+	require.Regexp(t, `^[abü]{10}$`, s)
+
+	s, err = randString(r, 0)
+	require.NoError(t, err)
+	require.Empty(t, s)
+
+	_, err = randString(r, -1)
+	require.EqualError(t, err, "RandString: negative length -1")
+	_, err = randString(r, 1, "")
+	require.EqualError(t, err, "RandString: empty alphabet")
+}
+
+func TestRandHex(t *testing.T) {
+	r := newRand(1, 1)
+	s, err := randHex(r, 8)
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9a-f]{16}$`, s)
+}
+
+func TestUUID(t *testing.T) {
+	r := newRand(1, 1)
+	v4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	v7 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := map[string]bool{}
+	prev := ""
+	for range 100 {
+		u := uuidV4(r)
+		require.Regexp(t, v4, u)
+		require.False(t, seen[u])
+		seen[u] = true
+
+		u = uuidV7(r)
+		require.Regexp(t, v7, u)
+		// the timestamp prefix is ordered
+		require.GreaterOrEqual(t, u[:13], prev)
+		prev = u[:13]
+	}
+}
+
+func TestRandTime(t *testing.T) {
+	r := newRand(1, 1)
+	for range 100 {
+		s, err := randTime(r, "2024-01-01", "2024-01-02T12:00:00Z")
+		require.NoError(t, err)
+
+		got, err := time.Parse(timeLayout, s)
+		require.NoError(t, err)
+		require.False(t, got.Before(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+		require.True(t, got.Before(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)))
+	}
+
+	_, err := randTime(r, "yesterday", "2024-01-01")
+	require.EqualError(t, err, `RandTime: invalid timestamp "yesterday", neither RFC 3339, "2006-01-02 15:04:05" nor "2006-01-02"`)
+	_, err = randTime(r, "2024-01-01", "2024-01-01")
+	require.EqualError(t, err, "RandTime: 2024-01-01 is not after 2024-01-01")
+}
+
+func TestRandChoice(t *testing.T) {
+	r := newRand(1, 1)
+	for range 100 {
+		got, err := randChoice(r, "a", int64(1), 2.5)
+		require.NoError(t, err)
+		require.Contains(t, []any{"a", int64(1), 2.5}, got)
+	}
+
+	_, err := randChoice(r)
+	require.EqualError(t, err, "RandChoice: no items")
+}
+
+func TestRandNames(t *testing.T) {
+	r := newRand(1, 1)
+	require.Contains(t, firstNames, randFirstName(r))
+	require.Contains(t, lastNames, randLastName(r))
+	require.Regexp(t, `^[A-Z][a-z]+ [A-Z][a-z]+$`, randName(r))
+	require.Regexp(t, `^[a-z]+\.[a-z]+[0-9]{1,4}@example\.(com|net|org)$`, randEmail(r))
+}
+
+func TestRenderStmtGenerators(t *testing.T) {
+	tmpl, err := parseTemplate("test", `{{call .RandString 5 "x"}} {{call .RandHex .Vars.n}} {{call .RandChoice "a" "a"}}`)
+	require.NoError(t, err)
+
+	got, _, err := renderStmt(tmpl, firstIteration(newRand(1, 1)), map[string]any{"n": int64(2)})
+	require.NoError(t, err)
+	require.Regexp(t, `^xxxxx [0-9a-f]{4} a$`, got)
+}
@@ -0,0 +1,113 @@
+package benchmark
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrIncludeCycle is raised when a script includes itself, directly or indirectly.
+var ErrIncludeCycle = errors.New("include cycle")
+
+// position is the origin of a script line.
+type position struct {
+	file string
+	line int
+}
+
+// scriptLine is a line of a script with included files already expanded.
+type scriptLine struct {
+	text string
+	pos  position
+}
+
+// ParseScriptFile parses the benchmark script of the given file. Included
+// files are resolved relative to the including file. The defined variables
+// are set before the variables of each benchmark, see ParseDefine.
+func ParseScriptFile(path string, defines ...Var) (Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Script{}, err
+	}
+	defer f.Close()
+
+	lines, errs, err := readLines(f, path, filepath.Dir(path), []string{absPath(path)})
+	if err != nil {
+		return Script{}, err
+	}
+	return parseLines(lines, path, errs, defines)
+}
+
+// readLines reads the lines of the script and replaces each '\include' line
+// with the lines of the included file. Relative paths are resolved from dir,
+// stack contains the files which are currently read to detect cycles.
+// Includes which fail are returned as ParseErrors and skipped, the remaining
+// lines are still read. Only failing reads of the script return an error.
+func readLines(r io.Reader, file, dir string, stack []string) ([]scriptLine, ParseErrors, error) {
+	lines := []scriptLine{}
+	errs := ParseErrors{}
+
+	scanner := bufio.NewScanner(r)
+	for lineN := 1; scanner.Scan(); lineN++ {
+		l := scriptLine{text: scanner.Text(), pos: position{file: file, line: lineN}}
+
+		tokens, cols := fields(strings.TrimSpace(l.text))
+		if len(tokens) == 0 || tokens[0] != "\\include" {
+			lines = append(lines, l)
+			continue
+		}
+
+		if len(tokens) == 1 {
+			errs = append(errs, errorAt(l, errors.New("missing path after \\include")))
+			continue
+		}
+
+		path := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l.text), "\\include"))
+		included, includeErrs, err := include(path, dir, stack)
+		if err != nil {
+			errs = append(errs, errorAt(l, &columnError{col: cols[1], err: err}))
+			continue
+		}
+		lines = append(lines, included...)
+		errs = append(errs, includeErrs...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read %v: %w", file, err)
+	}
+	return lines, errs, nil
+}
+
+// include reads the lines of the included file.
+func include(path, dir string, stack []string) ([]scriptLine, ParseErrors, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	abs := absPath(path)
+	if slices.Contains(stack, abs) {
+		cycle := slices.Concat(stack[slices.Index(stack, abs):], []string{abs})
+		return nil, nil, fmt.Errorf("%w: %v", ErrIncludeCycle, strings.Join(cycle, " -> "))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	return readLines(f, path, filepath.Dir(path), slices.Concat(stack, []string{abs}))
+}
+
+// absPath returns the absolute path, or the path itself when it can't be determined.
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
@@ -0,0 +1,99 @@
+package benchmark
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes the files with the given content into dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestParseScriptFileInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.sql": `\include common/schema.sql
+\benchmark loop \name inserts
+INSERT INTO t VALUES ({{.Iter}});
+\include common/reads.sql
+`,
+		"common/schema.sql": `\setup
+\include tables.sql
+\teardown
+DROP TABLE t;
+`,
+		"common/tables.sql": "CREATE TABLE t (id INT);\n",
+		"common/reads.sql": `\benchmark loop
+SELECT * FROM t;
+`,
+	})
+
+	got, err := ParseScriptFile(filepath.Join(dir, "main.sql"))
+	require.NoError(t, err)
+
+	require.Equal(t, Script{
+		Setup: []string{"CREATE TABLE t (id INT)"},
+		Benchmarks: []Benchmark{
+			{Name: "(loop) inserts", Type: TypeLoop, Stmt: "INSERT INTO t VALUES ({{.Iter}});"},
+			{Name: "(loop) common/reads.sql line 2-2", Type: TypeLoop, Stmt: "SELECT * FROM t;"},
+		},
+		Teardown: []string{"DROP TABLE t"},
+	}, got)
+}
+
+func TestParseScriptFileIncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"cycle.sql":             "SELECT 1;\n\\include sub/cycle.sql\n",
+		"sub/cycle.sql":         "\\include ../cycle.sql\n",
+		"missing.sql":           "SELECT 1;\n\\include missing-fragment.sql\n",
+		"invalid.sql":           "\\include fragments/invalid.sql\n",
+		"fragments/invalid.sql": "-- comment\n\\benchmark unknown-mode\n",
+		"empty.sql":             "\\include\n",
+		"continue.sql":          "\\include missing-fragment.sql\n\\benchmark loop\nSELECT 1;\n  \\include ../missing-fragment.sql\n\\benchmark unknown-mode\n",
+	})
+
+	_, err := ParseScriptFile(filepath.Join(dir, "cycle.sql"))
+	require.ErrorIs(t, err, ErrIncludeCycle)
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, filepath.Join(dir, "sub/cycle.sql"), parseErr.File)
+	require.Equal(t, 1, parseErr.Line)
+
+	_, err = ParseScriptFile(filepath.Join(dir, "missing.sql"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, filepath.Join(dir, "missing.sql"), parseErr.File)
+	require.Equal(t, 2, parseErr.Line)
+
+	// errors of included files point at the included file
+	_, err = ParseScriptFile(filepath.Join(dir, "invalid.sql"))
+	require.EqualError(t, err, filepath.Join(dir, "fragments/invalid.sql")+":2:12: failed to parse mode, neither 'once' nor 'loop': unknown-mode")
+
+	_, err = ParseScriptFile(filepath.Join(dir, "empty.sql"))
+	require.EqualError(t, err, filepath.Join(dir, "empty.sql")+":1:1: missing path after \\include")
+
+	// parsing continues after failed includes, all errors are reported
+	_, err = ParseScriptFile(filepath.Join(dir, "continue.sql"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	var parseErrs ParseErrors
+	require.ErrorAs(t, err, &parseErrs)
+	positions := []string{}
+	for _, e := range parseErrs {
+		positions = append(positions, fmt.Sprintf("%v:%v", e.Line, e.Col))
+	}
+	require.Equal(t, []string{"1:10", "4:12", "5:12"}, positions)
+
+	_, err = ParseScriptFile(filepath.Join(dir, "not-existing.sql"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,111 @@
+package benchmark
+
+import (
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Interval contains the metrics of a single reporting interval of a loop benchmark.
+type Interval struct {
+	Start        time.Time
+	Duration     time.Duration
+	SuccessCount uint64
+	ErrorCount   uint64
+	P50          time.Duration
+	P90          time.Duration
+	P95          time.Duration
+	P99          time.Duration
+	P999         time.Duration
+	Max          time.Duration
+}
+
+// OpsPerSec returns the throughput of successful executions in the interval.
+func (i Interval) OpsPerSec() float64 {
+	if i.Duration <= 0 {
+		return 0
+	}
+	return float64(i.SuccessCount) / i.Duration.Seconds()
+}
+
+// intervalStats collects the metrics of the current reporting interval.
+// The histogram is reused for all intervals of a benchmark.
+type intervalStats struct {
+	start     time.Time
+	successes uint64
+	errors    uint64
+	histogram *hdrhistogram.Histogram
+}
+
+// reportIntervals starts to collect the metrics in intervals of the given duration.
+// The returned func stops the reporting and reports the remaining partial interval.
+func (b *bencherExecutor) reportIntervals(opts Options) (stop func()) {
+	b.mux.Lock()
+	b.interval = &intervalStats{start: time.Now(), histogram: newHistogram()}
+	b.mux.Unlock()
+
+	ticker := time.NewTicker(opts.ReportInterval)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case <-ticker.C:
+				b.snapshot(opts, false)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+		<-finished
+		b.snapshot(opts, true)
+
+		b.mux.Lock()
+		b.interval = nil
+		b.mux.Unlock()
+	}
+}
+
+// snapshot finishes the current interval and starts a new one.
+// The last interval is skipped when nothing was executed.
+func (b *bencherExecutor) snapshot(opts Options, last bool) {
+	b.mux.Lock()
+
+	now := time.Now()
+	cur := b.interval
+	if last && cur.successes+cur.errors == 0 {
+		b.mux.Unlock()
+		return
+	}
+
+	interval := Interval{
+		Start:        cur.start,
+		Duration:     now.Sub(cur.start),
+		SuccessCount: cur.successes,
+		ErrorCount:   cur.errors,
+		P50:          time.Duration(cur.histogram.ValueAtPercentile(50)),
+		P90:          time.Duration(cur.histogram.ValueAtPercentile(90)),
+		P95:          time.Duration(cur.histogram.ValueAtPercentile(95)),
+		P99:          time.Duration(cur.histogram.ValueAtPercentile(99)),
+		P999:         time.Duration(cur.histogram.ValueAtPercentile(99.9)),
+		Max:          time.Duration(cur.histogram.Max()),
+	}
+	b.result.Intervals = append(b.result.Intervals, interval)
+
+	cur.start = now
+	cur.successes = 0
+	cur.errors = 0
+	cur.histogram.Reset()
+
+	b.mux.Unlock()
+
+	if opts.OnInterval != nil {
+		opts.OnInterval(b.name, interval)
+	}
+}
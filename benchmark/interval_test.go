@@ -0,0 +1,63 @@
+package benchmark
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoopIntervals(t *testing.T) {
+	// arrange
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil).Run(func(mock.Arguments) { time.Sleep(time.Millisecond) })
+	tmpl := template.Must(template.New("test").Parse("{{.Iter}}"))
+
+	executor := bencherExecutor{result: newResult(), name: "test"}
+
+	var (
+		mux      sync.Mutex
+		reported []Interval
+	)
+	opts := Options{
+		Threads:        2,
+		Duration:       55 * time.Millisecond,
+		ReportInterval: 10 * time.Millisecond,
+		OnInterval: func(name string, interval Interval) {
+			mux.Lock()
+			defer mux.Unlock()
+			assert.Equal(t, "test", name)
+			reported = append(reported, interval)
+		},
+	}
+
+	// act
+	executor.loop(context.Background(), bencher, single(tmpl), opts)
+
+	// assert
+	r := executor.result
+	require.GreaterOrEqual(t, len(r.Intervals), 5)
+	assert.Equal(t, r.Intervals, reported)
+
+	var successes uint64
+	for i, interval := range r.Intervals {
+		successes += interval.SuccessCount
+		assert.Positive(t, interval.OpsPerSec())
+		assert.GreaterOrEqual(t, interval.P99, interval.P50)
+		if i > 0 {
+			// no gaps between the intervals
+			assert.Equal(t, r.Intervals[i-1].Duration, interval.Start.Sub(r.Intervals[i-1].Start))
+		}
+	}
+	assert.Equal(t, r.SuccessCount, successes)
+}
+
+func TestIntervalOpsPerSec(t *testing.T) {
+	assert.Equal(t, 50.0, Interval{Duration: 2 * time.Second, SuccessCount: 100}.OpsPerSec())
+	assert.Equal(t, 0.0, Interval{}.OpsPerSec())
+}
@@ -0,0 +1,121 @@
+package benchmark
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"text/template"
+)
+
+// MixStmt is a statement of a mixed loop benchmark. Each iteration executes
+// one of the statements, chosen randomly according to their weights.
+type MixStmt struct {
+	Name   string
+	Weight int
+	Stmt   string
+}
+
+// statements contains the parsed templates and variables of a benchmark
+// and builds the statement of each iteration.
+type statements struct {
+	templates   []*template.Template
+	cumWeight   []int // cumulative weights of the mixed statements
+	vars        []compiledVar
+	delimiter   string             // splits the rendered template into the statements of the iteration
+	placeholder func(n int) string // see Options.Placeholder
+}
+
+// compiledVar is a variable with its parsed expression.
+type compiledVar struct {
+	name string
+	expr expr
+}
+
+// newStatements parses the statement of the benchmark,
+// or all statements when it's a mixed benchmark.
+func newStatements(b Benchmark) (statements, error) {
+	vars, err := compileVars(b.Vars)
+	if err != nil {
+		return statements{}, err
+	}
+	s := statements{vars: vars, delimiter: b.Delimiter}
+
+	if len(b.Mix) == 0 {
+		t, err := parseTemplate(b.Name, b.Stmt)
+		if err != nil {
+			return statements{}, err
+		}
+		s.templates = []*template.Template{t}
+		return s, nil
+	}
+
+	total := 0
+	for _, m := range b.Mix {
+		t, err := parseTemplate(m.Name, m.Stmt)
+		if err != nil {
+			return statements{}, err
+		}
+		total += m.Weight
+		s.templates = append(s.templates, t)
+		s.cumWeight = append(s.cumWeight, total)
+	}
+	return s, nil
+}
+
+// compileVars parses the expressions of the variables.
+func compileVars(vars []Var) ([]compiledVar, error) {
+	compiled := []compiledVar{}
+	for _, v := range vars {
+		e, _, err := parseExpr(v.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse \\set %v: %v", v.Name, err)
+		}
+		compiled = append(compiled, compiledVar{name: v.Name, expr: e})
+	}
+	return compiled, nil
+}
+
+// evalVars evaluates the variables of the iteration in order, the random
+// values are drawn from its generator. The expressions can reference
+// ':client_id' of the thread, it's not part of the result unless it was set.
+func evalVars(vars []compiledVar, it iteration) (map[string]any, error) {
+	values := map[string]any{clientIDVar: int64(it.thread - 1)}
+	for _, v := range vars {
+		value, err := v.expr.eval(it.rand, values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate \\set %v: %v", v.name, err)
+		}
+		values[v.name] = value
+	}
+	if !slices.ContainsFunc(vars, func(v compiledVar) bool { return v.name == clientIDVar }) {
+		delete(values, clientIDVar)
+	}
+	return values, nil
+}
+
+// build evaluates the variables and builds the statements of the iteration.
+// It returns the index of the executed mixed statement as well, also when
+// the variables or the template failed.
+func (s statements) build(it iteration) ([]query, int, error) {
+	mix := s.pick(it.rand)
+	vars, err := evalVars(s.vars, it)
+	if err != nil {
+		return nil, mix, err
+	}
+
+	stmt, args, err := renderStmt(s.templates[mix], it, vars)
+	if err != nil {
+		return nil, mix, fmt.Errorf("failed to execute template: %v", err)
+	}
+	return bindArgs(splitAll(stmt, s.delimiter), args, s.placeholder), mix, nil
+}
+
+// pick returns the index of the template to execute.
+func (s statements) pick(r *rand.Rand) int {
+	if len(s.templates) == 1 {
+		return 0
+	}
+	n := r.IntN(s.cumWeight[len(s.cumWeight)-1])
+	return sort.SearchInts(s.cumWeight, n+1)
+}
@@ -1,11 +1,15 @@
 package benchmark
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -13,129 +17,576 @@ var (
 	ErrNoMode = errors.New("failed to parse \\benchmark line, missing mode")
 	// ErrNoName is raised when there is no token after \name.
 	ErrNoName = errors.New("missing name after \\name token")
+	// ErrMixNotLoop is raised when there is a \mix outside of a loop benchmark.
+	ErrMixNotLoop = errors.New("\\mix is only supported in loop benchmarks")
+	// ErrMixStmt is raised when a mixed benchmark contains statements before the first \mix.
+	ErrMixStmt = errors.New("statements of a loop benchmark with \\mix have to follow a \\mix line")
+	// ErrMixNoStmt is raised when there is no statement after \mix.
+	ErrMixNoStmt = errors.New("missing statement after \\mix")
+	// ErrSetSection is raised when there is a \set in the setup or teardown section.
+	ErrSetSection = errors.New("\\set is only supported in benchmarks")
 )
 
-// Helper function to determine the benchmark name.
-func getName(benchmark Benchmark, start, line int) string {
+// varNameRegexp matches valid variable names, which can be used in templates.
+var varNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Helper function to determine the benchmark name, lines describes
+// the lines of the statement(s) when there is no custom name.
+func getName(benchmark Benchmark, lines string) string {
 	switch benchmark.Type {
 	case TypeLoop:
 		if benchmark.Name != "" {
 			return "(loop) " + benchmark.Name
 		}
-		return fmt.Sprintf("(loop) line %v-%v", start, line-1)
+		return "(loop) " + lines
 	case TypeOnce:
 		if benchmark.Name != "" {
 			return "(once) " + benchmark.Name
 		}
-		return fmt.Sprintf("(once) line %v", line)
+		return "(once) " + lines
 	}
 	return "" // shouldn't happen
 }
 
-// ParseScript parses a benchmark script and returns the benchmarks.
-func ParseScript(r io.Reader) ([]Benchmark, error) {
+// benchmarkOptions are the options of a '\benchmark' line.
+var benchmarkOptions = []string{"\\parallel", "\\breakdown", "\\name", "\\iter", "\\threads", "\\duration", "\\sleep"}
+
+// parseOptions parses the options after the mode of a '\benchmark' line,
+// cols are the columns of the tokens. Unknown options were ignored by older
+// versions, they are skipped with their value and returned as warnings.
+func parseOptions(b *Benchmark, tokens []string, cols []int) (warnings []error, err error) {
+	for i := 0; i < len(tokens); i++ {
+		option, optionCol := tokens[i], cols[i]
+
+		if !slices.Contains(benchmarkOptions, option) {
+			warnings = append(warnings, &columnError{col: optionCol, err: fmt.Errorf("ignored unknown option %v", option)})
+			// skip the value, unless it's the next option
+			if i+1 < len(tokens) && !strings.HasPrefix(tokens[i+1], "\\") {
+				i++
+			}
+			continue
+		}
+
+		// all options except '\parallel' and '\breakdown' require a value
+		value := ""
+		if option != "\\parallel" && option != "\\breakdown" {
+			if i+1 >= len(tokens) {
+				if option == "\\name" {
+					return warnings, &columnError{col: optionCol, err: ErrNoName}
+				}
+				return warnings, &columnError{col: optionCol, err: fmt.Errorf("missing value after %v", option)}
+			}
+			i++
+			value = tokens[i]
+		}
+
+		var err error
+		switch option {
+		case "\\parallel":
+			b.Parallel = true
+		case "\\breakdown":
+			b.Breakdown = true
+		case "\\name":
+			b.Name = value
+		case "\\iter":
+			b.Iter, err = parsePositive(value)
+		case "\\threads":
+			b.Threads, err = parsePositive(value)
+		case "\\duration":
+			b.Duration, err = parseDuration(value, false)
+		case "\\sleep":
+			b.Sleep, err = parseDuration(value, true)
+		}
+		if err != nil {
+			return warnings, &columnError{col: cols[i], err: fmt.Errorf("failed to parse %v: %v", option, err)}
+		}
+	}
+	return warnings, nil
+}
+
+// parseMix parses the weight and the options of a '\mix' line,
+// cols are the columns of the tokens.
+func parseMix(tokens []string, cols []int) (MixStmt, error) {
+	if len(tokens) == 0 {
+		return MixStmt{}, errors.New("missing weight after \\mix")
+	}
+
+	weight, err := parsePositive(tokens[0])
+	if err != nil {
+		return MixStmt{}, &columnError{col: cols[0], err: fmt.Errorf("failed to parse \\mix weight: %v", err)}
+	}
+	mix := MixStmt{Weight: weight}
+
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i] != "\\name" {
+			return MixStmt{}, &columnError{col: cols[i], err: fmt.Errorf("unknown option %v", tokens[i])}
+		}
+		if i+1 >= len(tokens) {
+			return MixStmt{}, &columnError{col: cols[i], err: ErrNoName}
+		}
+		i++
+		mix.Name = tokens[i]
+	}
+	return mix, nil
+}
+
+// parseSet parses a '\set name expr' line. The expression may only
+// reference the already defined variables.
+func parseSet(line string, defined []Var) (Var, error) {
+	tokens, cols := fields(line)
+	if len(tokens) < 2 {
+		return Var{}, errors.New("missing name after \\set")
+	}
+
+	name := tokens[1]
+	if !varNameRegexp.MatchString(name) {
+		return Var{}, &columnError{col: cols[1], err: fmt.Errorf("invalid variable name %q", name)}
+	}
+
+	v := Var{Name: name, Expr: strings.TrimSpace(line[cols[1]+len(name):])}
+	if v.Expr == "" {
+		return Var{}, &columnError{col: cols[1], err: fmt.Errorf("missing expression after \\set %v", name)}
+	}
+	// the line is trimmed, the expression is at the end of it
+	if err := checkExpr(v.Expr, len(line)-len(v.Expr), defined, "\\set "+name); err != nil {
+		return Var{}, err
+	}
+	return v, nil
+}
+
+// defineSet parses the '\set' line and appends its variable to the defined
+// variables. An invalid variable is defined anyway, statements using it
+// would fail as well otherwise.
+func defineSet(line string, defined []Var) ([]Var, error) {
+	v, err := parseSet(line, defined)
+	if err != nil {
+		if tokens := strings.Fields(line); len(tokens) > 1 && varNameRegexp.MatchString(tokens[1]) {
+			defined = append(defined, Var{Name: tokens[1], Expr: "0"})
+		}
+		return defined, err
+	}
+	return append(defined, v), nil
+}
+
+// ParseDefine parses a 'name=expression' definition of a variable, e.g. of
+// the command line. The expression can only reference ':client_id'.
+func ParseDefine(s string) (Var, error) {
+	name, expr, ok := strings.Cut(s, "=")
+	if !ok {
+		return Var{}, fmt.Errorf("missing '=' in %q", s)
+	}
+	v := Var{Name: strings.TrimSpace(name), Expr: strings.TrimSpace(expr)}
+	if !varNameRegexp.MatchString(v.Name) {
+		return Var{}, fmt.Errorf("invalid variable name %q", v.Name)
+	}
+	if v.Expr == "" {
+		return Var{}, fmt.Errorf("missing expression after %v=", v.Name)
+	}
+	if err := checkExpr(v.Expr, 0, nil, v.Name); err != nil {
+		return Var{}, err
+	}
+	return v, nil
+}
+
+// clientIDVar is the variable of the expressions with the number of the
+// thread starting at 0, like ':client_id' of pgbench.
+const clientIDVar = "client_id"
+
+// checkExpr parses the expression of the command, which starts at the column
+// of the line. The expression may only reference the already defined variables
+// and ':client_id'.
+func checkExpr(expr string, exprCol int, defined []Var, command string) error {
+	_, refs, err := parseExpr(expr)
+	if err != nil {
+		col := exprCol
+		var exprErr *exprError
+		if errors.As(err, &exprErr) {
+			col += exprErr.pos
+		}
+		return &columnError{col: col, err: fmt.Errorf("failed to parse %v: %v", command, err)}
+	}
+
+	for _, ref := range refs {
+		if ref != clientIDVar && !slices.ContainsFunc(defined, func(d Var) bool { return d.Name == ref }) {
+			col := exprCol + max(strings.Index(expr, ":"+ref), 0)
+			return &columnError{col: col, err: fmt.Errorf("undefined variable :%v in %v", ref, command)}
+		}
+	}
+	return nil
+}
+
+// parsePositive parses a number greater than zero.
+func parsePositive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("%v is not greater than 0", n)
+	}
+	return n, nil
+}
+
+// parseDuration parses a duration greater than zero, or not less than zero
+// when zero is allowed.
+func parseDuration(s string, allowZero bool) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case d < 0 && allowZero:
+		return 0, fmt.Errorf("%v is less than 0", d)
+	case d <= 0 && !allowZero:
+		return 0, fmt.Errorf("%v is not greater than 0", d)
+	}
+	return d, nil
+}
+
+// Script contains the parsed statements of a benchmark script.
+type Script struct {
+	// Setup statements are executed once before the benchmarks, not measured.
+	Setup []string
+	// Benchmarks are measured.
+	Benchmarks []Benchmark
+	// Teardown statements are executed once after the benchmarks, not measured.
+	Teardown []string
+	// Warnings point at the parts of the script which were ignored or
+	// probably don't do what was intended.
+	Warnings ParseErrors
+}
+
+// section is the part of the script the current line belongs to.
+type section int
+
+const (
+	sectionBenchmark section = iota
+	sectionSetup
+	sectionTeardown
+)
+
+// ParseScript parses a benchmark script and returns the setup statements,
+// the benchmarks and the teardown statements. Included files are resolved
+// relative to the working directory. The defined variables are set before
+// the variables of each benchmark, see ParseDefine.
+func ParseScript(r io.Reader, defines ...Var) (Script, error) {
+	lines, errs, err := readLines(r, "", ".", nil)
+	if err != nil {
+		return Script{}, err
+	}
+	return parseLines(lines, "", errs, defines)
+}
+
+// parseLines parses the lines of the script, root is the file of the main script.
+// All errors of the script, including the given errors of reading its lines,
+// are returned as ParseErrors.
+func parseLines(lines []scriptLine, root string, readErrs ParseErrors, defines []Var) (Script, error) {
 	var (
-		scanner    = bufio.NewScanner(r)
-		loopStart  = 1             // line the current loop mode started
-		lineN      = 1             // current line number
+		loopStart  = 0             // index of the line the current loop mode started
 		benchmarks = []Benchmark{} // the result
-		curBench   = Benchmark{Type: TypeLoop, Parallel: false}
+		curBench   = Benchmark{Type: TypeLoop, Parallel: false, Vars: slices.Clone(defines)}
+		curSection = sectionBenchmark
+		curMix     *MixStmt // current statement of a mixed benchmark
+		mixStart   = 0      // index of the line the current mixed statement started
+		script     = Script{Setup: []string{}, Teardown: []string{}}
+		stmtLines  = []scriptLine{}   // lines of the current loop or mixed statement
+		mixLines   = [][]scriptLine{} // lines of the finished mixed statements of the current loop
+		checks     = []stmtCheck{}    // statements to check after parsing
+		errs       = slices.Clone(readErrs)
+		delimiter  = defaultDelimiter // separates the statements, changed with '\delimiter'
+		pending    = ""               // incomplete statement of the setup, teardown or a once benchmark
+		pendLines  = []scriptLine{}   // lines of the pending statement
+		pendStart  = 0                // index of the line the pending statement started
+		pendEnd    = 0                // index of the last line of the pending statement
 	)
 
-	// Helper function to append a new loop benchmark
-	flushLoop := func() {
-		if curBench.Stmt != "" {
+	// Helper function to describe the line at the index,
+	// lines of included files are prefixed with the file
+	lineAt := func(i int) string {
+		pos := lines[i].pos
+		if pos.file != root {
+			return fmt.Sprintf("%v line %v", relPath(root, pos.file), pos.line)
+		}
+		return fmt.Sprintf("line %v", pos.line)
+	}
+
+	// Helper function to describe the lines from start to end (indices)
+	lineRange := func(start, end int) string {
+		from, to := lines[start].pos, lines[end].pos
+		if from.file != to.file {
+			return lineAt(start) + "-" + lineAt(end)
+		}
+		return fmt.Sprintf("%v-%v", lineAt(start), to.line)
+	}
+
+	// Helper function to append the current statement of a mixed benchmark,
+	// i is the index of the line which ended the statement
+	flushMix := func(i int) {
+		if curMix == nil {
+			return
+		}
+		defer func() { curMix, stmtLines = nil, []scriptLine{} }()
+
+		if curMix.Stmt == "" {
+			errs = append(errs, errorAt(lines[mixStart-1], ErrMixNoStmt))
+			return
+		}
+		curMix.Stmt = strings.TrimSuffix(curMix.Stmt, "\n")
+		if curMix.Name == "" {
+			curMix.Name = lineRange(mixStart, i-1)
+		}
+		curBench.Mix = append(curBench.Mix, *curMix)
+		mixLines = append(mixLines, stmtLines)
+	}
+
+	// Helper function to append a new loop benchmark,
+	// i is the index of the line which ended the benchmark
+	flushLoop := func(i int) {
+		flushMix(i)
+		if curBench.Stmt != "" || len(curBench.Mix) > 0 {
 			curBench.Stmt = strings.TrimSuffix(curBench.Stmt, "\n")
-			curBench.Name = getName(curBench, loopStart, lineN)
+			curBench.Name = getName(curBench, lineRange(loopStart, i-1))
 			benchmarks = append(benchmarks, curBench)
 
+			if curBench.Stmt != "" {
+				checks = append(checks, stmtCheck{stmt: curBench.Stmt, lines: stmtLines, vars: curBench.Vars})
+			}
+			for j, m := range curBench.Mix {
+				checks = append(checks, stmtCheck{stmt: m.Stmt, lines: mixLines[j], vars: curBench.Vars})
+			}
+
 			// Start new empty benchmark
-			curBench = Benchmark{}
+			curBench = Benchmark{Vars: slices.Clone(defines)}
+		}
+		stmtLines, mixLines = []scriptLine{}, [][]scriptLine{}
+	}
+
+	// Helper function to append a complete statement of the setup, the teardown
+	// or a once benchmark, start and end are the indices of its lines
+	appendStmt := func(stmt string, start, end int, stmtLines []scriptLine) {
+		// each line used to be a statement of its own, before statements
+		// were split at the delimiter
+		if len(stmtLines) > 1 && !slices.ContainsFunc(stmtLines, func(l scriptLine) bool { return !looksComplete(strings.TrimSpace(l.text)) }) {
+			script.Warnings = append(script.Warnings, errorAt(stmtLines[1], fmt.Errorf("missing %q before this line, the lines are executed as one statement", delimiter)))
 		}
+
+		switch curSection {
+		case sectionSetup:
+			script.Setup = append(script.Setup, stmt)
+			checks = append(checks, stmtCheck{stmt: stmt, lines: stmtLines, setup: true})
+			return
+		case sectionTeardown:
+			script.Teardown = append(script.Teardown, stmt)
+			checks = append(checks, stmtCheck{stmt: stmt, lines: stmtLines, setup: true})
+			return
+		}
+
+		name := lineAt(start)
+		if start != end {
+			name = lineRange(start, end)
+		}
+		b := curBench
+		b.Name = getName(curBench, name)
+		b.Stmt = stmt + delimiter
+		if delimiter != defaultDelimiter {
+			b.Delimiter = delimiter
+		}
+		benchmarks = append(benchmarks, b)
+		checks = append(checks, stmtCheck{stmt: stmt, lines: stmtLines, vars: curBench.Vars})
+
+		// As long as there is no mode change, keep it TypeOnce, which is the non-default mode.
+		// The options of the block apply to all of its statements, the name only to the first.
+		curBench = Benchmark{Type: TypeOnce, Parallel: curBench.Parallel, Sleep: curBench.Sleep, Vars: curBench.Vars}
+	}
+
+	// Helper function to append the pending statement, which is missing the delimiter
+	flushPending := func() {
+		if pending != "" {
+			appendStmt(strings.TrimSpace(pending), pendStart, pendEnd, pendLines)
+		}
+		pending, pendLines = "", []scriptLine{}
 	}
 
 	// Parse each line of the script file
-	for ; scanner.Scan(); lineN++ {
-		line := strings.TrimSpace(scanner.Text())
+	for i, l := range lines {
+		line := strings.TrimSpace(l.text)
 
 		// Skip comments and empty lines.
 		if strings.HasPrefix(line, "--") || line == "" {
 			continue
 		}
 
+		// A command ends the pending statement.
+		if strings.HasPrefix(line, "\\") {
+			flushPending()
+		}
+
+		// Parse '\setup' and '\teardown' commands.
+		if line == "\\setup" || line == "\\teardown" {
+			flushLoop(i)
+			curSection = sectionSetup
+			if line == "\\teardown" {
+				curSection = sectionTeardown
+			}
+			continue
+		}
+
 		// Parse '\benchmark' command.
 		if strings.HasPrefix(line, "\\benchmark") {
-			tokens := strings.Split(line, " ")
+			curSection = sectionBenchmark
+
+			tokens, cols := fields(line)
 
 			// remove '\benchmark' entry from tokens
-			tokens = tokens[1:]
+			tokens, cols = tokens[1:], cols[1:]
 
 			if len(tokens) <= 0 {
 				// line does only consist of the token '\benchmark', we need more info
-				return []Benchmark{}, ErrNoMode
+				errs = append(errs, errorAt(l, ErrNoMode))
+				continue
 			}
 
 			// parse benchmark mode 'once' or 'loop'
 			switch tokens[0] {
 			case "once":
 				if curBench.Type == TypeLoop {
-					flushLoop()
+					flushLoop(i)
 				}
-				curBench.Type = TypeOnce
+				// don't inherit the options of a previous block
+				curBench = Benchmark{Type: TypeOnce, Vars: slices.Clone(defines)}
 			case "loop":
-				flushLoop()
-				curBench.Type = TypeLoop
-				loopStart = lineN + 1
+				flushLoop(i)
+				curBench = Benchmark{Type: TypeLoop, Vars: slices.Clone(defines)}
+				loopStart = i + 1
 			default:
-				return []Benchmark{}, fmt.Errorf("failed to parse mode, neither 'once' nor 'loop': %v", tokens[0])
+				errs = append(errs, errorAt(l, &columnError{col: cols[0], err: fmt.Errorf("failed to parse mode, neither 'once' nor 'loop': %v", tokens[0])}))
+				continue
 			}
 			// remove the mode token from the tokens
-			tokens = tokens[1:]
+			tokens, cols = tokens[1:], cols[1:]
 
 			// Parse remaining tokens
-			for _, t := range tokens {
-				// reminder: can't change 'tokens' inside the range, e.g. 'cutting' with tokens[2:]
-				// so we have to iterate even the token after \name, which could be skipped otherwise.
-				switch t {
-				case "\\parallel":
-					curBench.Parallel = true
-				case "\\name":
-					if len(tokens) < 2 {
-						return []Benchmark{}, ErrNoName
-					}
-					curBench.Name = tokens[1]
-				}
+			warnings, err := parseOptions(&curBench, tokens, cols)
+			for _, w := range warnings {
+				script.Warnings = append(script.Warnings, errorAt(l, w))
+			}
+			if err != nil {
+				errs = append(errs, errorAt(l, err))
 			}
 
 			// don't append '\benchmark' line
 			continue
 		}
 
+		// Parse '\set' command, which sets a variable before each iteration.
+		if tokens, cols := fields(line); tokens[0] == "\\set" {
+			if curSection != sectionBenchmark {
+				errs = append(errs, errorAt(l, ErrSetSection))
+				continue
+			}
+			var err error
+			if curBench.Vars, err = defineSet(line, curBench.Vars); err != nil {
+				errs = append(errs, errorAt(l, err))
+			}
+			continue
+		} else if tokens[0] == "\\mix" {
+			// Parse '\mix' command, which starts the next statement of a mixed benchmark.
+			if curSection != sectionBenchmark || curBench.Type != TypeLoop {
+				errs = append(errs, errorAt(l, ErrMixNotLoop))
+				continue
+			}
+			if curBench.Stmt != "" {
+				errs = append(errs, errorAt(l, ErrMixStmt))
+				continue
+			}
+			flushMix(i)
+
+			mix, err := parseMix(tokens[1:], cols[1:])
+			if err != nil {
+				errs = append(errs, errorAt(l, err))
+				continue
+			}
+			curMix = &mix
+			mixStart = i + 1
+			continue
+		} else if tokens[0] == "\\delimiter" {
+			// Parse '\delimiter' command, which changes the delimiter of the following statements.
+			switch len(tokens) {
+			case 1:
+				delimiter = defaultDelimiter
+			case 2:
+				delimiter = tokens[1]
+			default:
+				errs = append(errs, errorAt(l, &columnError{col: cols[2], err: fmt.Errorf("unexpected %v after \\delimiter", tokens[2])}))
+			}
+			continue
+		}
+
 		// Neither a '\benchmark' nor '\name' command line.
 		// Should be an SQL statement line.
-		// Append the line either as benchmark type once
-		// or append line for loop benchmark.
-		switch curBench.Type {
-		case TypeOnce:
-			// Once, append benchmark immediately.
-			curBench.Type = TypeOnce
-			curBench.Name = getName(curBench, loopStart, lineN)
-			curBench.Stmt = line
-			benchmarks = append(benchmarks, curBench)
-			// As long as there is no mode change, keep it TypeOnce, which is the non-default mode.
-			curBench = Benchmark{Type: TypeOnce}
-		case TypeLoop:
-			// Loop, but not finished yet, only append the line to the statement.
-			curBench.Stmt += line + "\n"
+		if curSection != sectionBenchmark || curBench.Type == TypeOnce {
+			// Setup, teardown and once benchmarks execute each statement on its own.
+			if pending == "" {
+				pendStart = i
+			}
+			pending += line + "\n"
+			pendLines, pendEnd = append(pendLines, l), i
+
+			stmts, rest := splitStatements(pending, delimiter)
+			for j, stmt := range stmts {
+				if j == 0 {
+					appendStmt(stmt, pendStart, i, pendLines)
+					continue
+				}
+				// further statements on the same line
+				appendStmt(stmt, i, i, []scriptLine{l})
+			}
+			if len(stmts) > 0 {
+				pending, pendStart, pendLines = rest, i, []scriptLine{}
+				if rest != "" {
+					pendLines = append(pendLines, l)
+				}
+			}
+			continue
+		}
+
+		// Loop, but not finished yet, only append the line to the statement.
+		if delimiter != defaultDelimiter {
+			curBench.Delimiter = delimiter
+		}
+		stmtLines = append(stmtLines, l)
+		if curMix != nil {
+			curMix.Stmt += line + "\n"
+			continue
+		}
+		curBench.Stmt += line + "\n"
+	}
+
+	// reached the end of the file, append remaining statements
+	flushPending()
+	flushLoop(len(lines))
+
+	// compile and render each statement
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	// reached the end of the file, append remaining loop statements to benchmark
-	if curBench.Stmt != "" {
-		curBench.Stmt = strings.TrimSuffix(curBench.Stmt, "\n")
-		curBench.Name = getName(curBench, loopStart, lineN)
-		benchmarks = append(benchmarks, curBench)
+	if len(errs) > 0 {
+		errs.sort()
+		return Script{}, errs
 	}
 
-	return benchmarks, nil
+	script.Benchmarks = benchmarks
+	return script, nil
+}
+
+// relPath returns the path of the included file relative to the directory of the main script.
+func relPath(root, file string) string {
+	rel, err := filepath.Rel(filepath.Dir(root), file)
+	if err != nil {
+		return file
+	}
+	return rel
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -23,24 +24,21 @@ func TestParseScript(t *testing.T) {
 			description: "fail/no mode",
 			in:          "\\benchmark",
 			expect: expect{
-				benchmarks: []Benchmark{},
-				err:        ErrNoMode,
+				err: ErrNoMode,
 			},
 		},
 		{
 			description: "fail/unknown mode",
 			in:          "\\benchmark unknown-mode",
 			expect: expect{
-				benchmarks: []Benchmark{},
-				err:        errors.New("failed to parse mode, neither 'once' nor 'loop': unknown-mode"),
+				err: errors.New("failed to parse mode, neither 'once' nor 'loop': unknown-mode"),
 			},
 		},
 		{
 			description: "fail/missing name",
 			in:          "\\benchmark once \\name",
 			expect: expect{
-				benchmarks: []Benchmark{},
-				err:        ErrNoName,
+				err: ErrNoName,
 			},
 		},
 		{
@@ -286,6 +284,300 @@ func TestParseScript(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "parallel/once block",
+			in: `
+			\benchmark once \parallel
+			INSERT INTO ...;
+			UPDATE ...;
+			\benchmark once
+			DELETE ...;
+			`,
+			expect: expect{
+				benchmarks: []Benchmark{
+					{Name: "(once) line 3", Type: TypeOnce, Parallel: true, Stmt: "INSERT INTO ...;"},
+					{Name: "(once) line 4", Type: TypeOnce, Parallel: true, Stmt: "UPDATE ...;"},
+					{Name: "(once) line 6", Type: TypeOnce, Stmt: "DELETE ...;"},
+				},
+			},
+		},
+		{
+			description: "options",
+			in: `
+			\benchmark loop \name inserts \iter 500  \threads 10 \duration 30s \sleep 2s
+			INSERT INTO ...;
+			\benchmark loop
+			DELETE ...;
+			`,
+			expect: expect{
+				benchmarks: []Benchmark{
+					{Name: "(loop) inserts", Type: TypeLoop, Stmt: "INSERT INTO ...;", Iter: 500, Threads: 10, Duration: 30 * time.Second, Sleep: 2 * time.Second},
+					{Name: "(loop) line 5-6", Type: TypeLoop, Stmt: "DELETE ...;"},
+				},
+			},
+		},
+		{
+			description: "options/breakdown",
+			in: `
+			\benchmark loop \breakdown \name tx
+			BEGIN;
+			COMMIT;
+			`,
+			expect: expect{
+				benchmarks: []Benchmark{
+					{Name: "(loop) tx", Type: TypeLoop, Stmt: "BEGIN;\nCOMMIT;", Breakdown: true},
+				},
+			},
+		},
+		{
+			description: "options/once block",
+			in: `
+			\benchmark once \sleep 1s
+			INSERT INTO ...;
+			UPDATE ...;
+			`,
+			expect: expect{
+				benchmarks: []Benchmark{
+					{Name: "(once) line 3", Type: TypeOnce, Stmt: "INSERT INTO ...;", Sleep: time.Second},
+					{Name: "(once) line 4", Type: TypeOnce, Stmt: "UPDATE ...;", Sleep: time.Second},
+				},
+			},
+		},
+		{
+			description: "fail/missing option value",
+			in:          "\\benchmark loop \\iter",
+			expect: expect{
+				err: errors.New("missing value after \\iter"),
+			},
+		},
+		{
+			description: "fail/invalid iter",
+			in:          "\\benchmark loop \\iter 0",
+			expect: expect{
+				err: errors.New("failed to parse \\iter: 0 is not greater than 0"),
+			},
+		},
+		{
+			description: "fail/zero duration",
+			in:          "\\benchmark loop \\duration 0s",
+			expect: expect{
+				err: errors.New("failed to parse \\duration: 0s is not greater than 0"),
+			},
+		},
+		{
+			description: "fail/negative sleep",
+			in:          "\\benchmark once \\sleep -1s",
+			expect: expect{
+				err: errors.New("failed to parse \\sleep: -1s is less than 0"),
+			},
+		},
+		{
+			description: "fail/invalid duration",
+			in:          "\\benchmark loop \\duration 30",
+			expect: expect{
+				err: errors.New(`failed to parse \duration: time: missing unit in duration "30"`),
+			},
+		},
+		{
+			description: "mix",
+			in: `
+			\benchmark loop \name oltp \threads 4
+			\mix 80 \name select
+			SELECT ...;
+			\mix 15
+			UPDATE ...;
+			-- comment
+			\mix 5 \name insert
+			BEGIN;
+			INSERT ...;
+			COMMIT;
+			\benchmark loop
+			DELETE ...;
+			`,
+			expect: expect{
+				benchmarks: []Benchmark{
+					{Name: "(loop) oltp", Type: TypeLoop, Threads: 4, Mix: []MixStmt{
+						{Name: "select", Weight: 80, Stmt: "SELECT ...;"},
+						{Name: "line 6-7", Weight: 15, Stmt: "UPDATE ...;"},
+						{Name: "insert", Weight: 5, Stmt: "BEGIN;\nINSERT ...;\nCOMMIT;"},
+					}},
+					{Name: "(loop) line 13-14", Type: TypeLoop, Stmt: "DELETE ...;"},
+				},
+			},
+		},
+		{
+			description: "fail/mix in once benchmark",
+			in: `
+			\benchmark once
+			\mix 1
+			SELECT ...;
+			`,
+			expect: expect{
+				err: ErrMixNotLoop,
+			},
+		},
+		{
+			description: "fail/statement before mix",
+			in: `
+			SELECT ...;
+			\mix 1
+			UPDATE ...;
+			`,
+			expect: expect{
+				err: ErrMixStmt,
+			},
+		},
+		{
+			description: "fail/mix without statement",
+			in: `
+			\mix 1
+			\mix 2
+			UPDATE ...;
+			`,
+			expect: expect{
+				err: ErrMixNoStmt,
+			},
+		},
+		{
+			description: "fail/mix without weight",
+			in:          "\\mix",
+			expect: expect{
+				err: errors.New("missing weight after \\mix"),
+			},
+		},
+		{
+			description: "fail/mix invalid weight",
+			in:          "\\mix 0",
+			expect: expect{
+				err: errors.New("failed to parse \\mix weight: 0 is not greater than 0"),
+			},
+		},
+		{
+			description: "set",
+			in: `
+			\set scale 10
+			\set aid random(1, 100000 * :scale)
+			INSERT INTO t VALUES ({{.Vars.aid}});
+			DELETE FROM t WHERE id = {{.Vars.aid}};
+			\benchmark once
+			\set id 1
+			INSERT ...;
+			UPDATE ...;
+			`,
+			expect: expect{
+				benchmarks: []Benchmark{
+					{Name: "(loop) line 1-5", Type: TypeLoop, Stmt: "INSERT INTO t VALUES ({{.Vars.aid}});\nDELETE FROM t WHERE id = {{.Vars.aid}};", Vars: []Var{
+						{Name: "scale", Expr: "10"},
+						{Name: "aid", Expr: "random(1, 100000 * :scale)"},
+					}},
+					{Name: "(once) line 8", Type: TypeOnce, Stmt: "INSERT ...;", Vars: []Var{{Name: "id", Expr: "1"}}},
+					{Name: "(once) line 9", Type: TypeOnce, Stmt: "UPDATE ...;", Vars: []Var{{Name: "id", Expr: "1"}}},
+				},
+			},
+		},
+		{
+			description: "fail/set undefined variable",
+			in:          "\\set aid random(1, :scale)",
+			expect: expect{
+				err: errors.New("undefined variable :scale in \\set aid"),
+			},
+		},
+		{
+			description: "fail/set invalid expression",
+			in:          "\\set aid random(1",
+			expect: expect{
+				err: errors.New("failed to parse \\set aid: missing ')' at position 9"),
+			},
+		},
+		{
+			description: "fail/set missing expression",
+			in:          "\\set aid",
+			expect: expect{
+				err: errors.New("missing expression after \\set aid"),
+			},
+		},
+		{
+			description: "fail/set invalid name",
+			in:          "\\set a-id 1",
+			expect: expect{
+				err: errors.New(`invalid variable name "a-id"`),
+			},
+		},
+		{
+			description: "fail/set in setup",
+			in: `
+			\setup
+			\set aid 1
+			`,
+			expect: expect{
+				err: ErrSetSection,
+			},
+		},
+		{
+			description: "once/multi-line statement",
+			in: `
+			\benchmark once
+			CREATE FUNCTION f() RETURNS int AS $$
+			BEGIN
+				RETURN 1;
+			END;
+			$$ LANGUAGE plpgsql;
+			SELECT f(); SELECT 'a;b';
+			`,
+			expect: expect{
+				benchmarks: []Benchmark{
+					{Name: "(once) line 3-7", Type: TypeOnce, Stmt: "CREATE FUNCTION f() RETURNS int AS $$\nBEGIN\nRETURN 1;\nEND;\n$$ LANGUAGE plpgsql;"},
+					{Name: "(once) line 8", Type: TypeOnce, Stmt: "SELECT f();"},
+					{Name: "(once) line 8", Type: TypeOnce, Stmt: "SELECT 'a;b';"},
+				},
+			},
+		},
+		{
+			description: "once/missing delimiter",
+			in: `
+			\benchmark once
+			SELECT 1
+			\benchmark once
+			SELECT 2
+			`,
+			expect: expect{
+				benchmarks: []Benchmark{
+					{Name: "(once) line 3", Type: TypeOnce, Stmt: "SELECT 1;"},
+					{Name: "(once) line 5", Type: TypeOnce, Stmt: "SELECT 2;"},
+				},
+			},
+		},
+		{
+			description: "delimiter",
+			in: `
+			\delimiter //
+			\benchmark once
+			CREATE PROCEDURE p()
+			BEGIN
+				SELECT 1;
+			END //
+			\benchmark loop
+			CALL p()//
+			CALL p()//
+			\delimiter
+			\benchmark loop
+			SELECT 1;
+			`,
+			expect: expect{
+				benchmarks: []Benchmark{
+					{Name: "(once) line 4-7", Type: TypeOnce, Stmt: "CREATE PROCEDURE p()\nBEGIN\nSELECT 1;\nEND//", Delimiter: "//"},
+					{Name: "(loop) line 9-11", Type: TypeLoop, Stmt: "CALL p()//\nCALL p()//", Delimiter: "//"},
+					{Name: "(loop) line 13-14", Type: TypeLoop, Stmt: "SELECT 1;"},
+				},
+			},
+		},
+		{
+			description: "fail/delimiter",
+			in:          "\\delimiter // ;",
+			expect: expect{
+				err: errors.New("unexpected ; after \\delimiter"),
+			},
+		},
 	}
 
 	for _, tt := range testCases {
@@ -294,10 +586,103 @@ func TestParseScript(t *testing.T) {
 
 			// act
 			got, err := ParseScript(r)
-			require.Equal(t, tt.expect.err, err)
 
 			// assert
-			require.Equal(t, tt.expect.benchmarks, got)
+			if tt.expect.err != nil {
+				var parseErr *ParseError
+				require.ErrorAs(t, err, &parseErr)
+				require.Equal(t, tt.expect.err, parseErr.Err)
+				require.Equal(t, Script{}, got)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expect.benchmarks, got.Benchmarks)
 		})
 	}
 }
+
+func TestParseScriptWarnings(t *testing.T) {
+	// unknown options were ignored by older versions
+	got, err := ParseScript(strings.NewReader("\\benchmark loop \\iterations 10 \\name x \\verbose \\threads 2\nSELECT 1;"))
+	require.NoError(t, err)
+	require.Len(t, got.Benchmarks, 1)
+	require.Equal(t, "(loop) x", got.Benchmarks[0].Name)
+	require.Equal(t, 2, got.Benchmarks[0].Threads)
+	require.EqualError(t, got.Warnings, "line 1:17: ignored unknown option \\iterations\nline 1:40: ignored unknown option \\verbose")
+}
+
+func TestParseScriptJoinedLinesWarnings(t *testing.T) {
+	in := `\setup
+CREATE TABLE t (
+  id INT
+);
+\benchmark once
+INSERT INTO t VALUES (1)
+INSERT INTO t VALUES (2)
+SELECT 1; SELECT 2
+SELECT 3;
+\teardown
+DROP TABLE t`
+	got, err := ParseScript(strings.NewReader(in))
+	require.NoError(t, err)
+	require.Equal(t, []string{"CREATE TABLE t (\nid INT\n)"}, got.Setup)
+	require.Len(t, got.Benchmarks, 2)
+	require.EqualError(t, got.Warnings, strings.Join([]string{
+		`line 7:1: missing ";" before this line, the lines are executed as one statement`,
+		`line 9:1: missing ";" before this line, the lines are executed as one statement`,
+	}, "\n"))
+}
+
+func TestParseScriptDefines(t *testing.T) {
+	in := `\set aid random(1, 100 * :scale)
+SELECT {{.Vars.aid}};
+\benchmark once
+SELECT {{.Vars.scale}};`
+	got, err := ParseScript(strings.NewReader(in), Var{Name: "scale", Expr: "10"})
+	require.NoError(t, err)
+	require.Equal(t, []Benchmark{
+		{Name: "(loop) line 1-2", Type: TypeLoop, Stmt: "SELECT {{.Vars.aid}};", Vars: []Var{{Name: "scale", Expr: "10"}, {Name: "aid", Expr: "random(1, 100 * :scale)"}}},
+		{Name: "(once) line 4", Type: TypeOnce, Stmt: "SELECT {{.Vars.scale}};", Vars: []Var{{Name: "scale", Expr: "10"}}},
+	}, got.Benchmarks)
+}
+
+func TestParseDefine(t *testing.T) {
+	v, err := ParseDefine("scale = 10 * 2")
+	require.NoError(t, err)
+	require.Equal(t, Var{Name: "scale", Expr: "10 * 2"}, v)
+
+	_, err = ParseDefine("scale")
+	require.EqualError(t, err, `missing '=' in "scale"`)
+	_, err = ParseDefine("a-b=1")
+	require.EqualError(t, err, `invalid variable name "a-b"`)
+	_, err = ParseDefine("scale=")
+	require.EqualError(t, err, "missing expression after scale=")
+	_, err = ParseDefine("scale=:other")
+	require.EqualError(t, err, "undefined variable :other in scale")
+}
+
+func TestParseScriptSetupTeardown(t *testing.T) {
+	in := `
+	\setup
+	CREATE TABLE t (id INT);
+	INSERT INTO t VALUES (1);
+	\benchmark loop \name select
+	SELECT * FROM t;
+	\teardown
+	DROP TABLE t;
+	\benchmark once \name count
+	SELECT COUNT(*) FROM t;
+	`
+
+	got, err := ParseScript(strings.NewReader(in))
+	require.NoError(t, err)
+
+	require.Equal(t, Script{
+		Setup: []string{"CREATE TABLE t (id INT)", "INSERT INTO t VALUES (1)"},
+		Benchmarks: []Benchmark{
+			{Name: "(loop) select", Type: TypeLoop, Stmt: "SELECT * FROM t;"},
+			{Name: "(once) count", Type: TypeOnce, Stmt: "SELECT COUNT(*) FROM t;"},
+		},
+		Teardown: []string{"DROP TABLE t"},
+	}, got)
+}
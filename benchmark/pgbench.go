@@ -0,0 +1,375 @@
+package benchmark
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoStatements is raised when a pgbench script doesn't contain any SQL statement.
+var ErrNoStatements = errors.New("script contains no SQL statements")
+
+// pgbenchUnsupported are the pgbench meta-commands which can't be translated.
+var pgbenchUnsupported = []string{"\\shell", "\\setshell", "\\gset", "\\aset", "\\startpipeline", "\\endpipeline", "\\syncpipeline"}
+
+// pgbenchBuiltins are the variables pgbench sets automatically, except ':scale' and ':client_id'.
+var pgbenchBuiltins = []string{"default_seed", "random_seed"}
+
+// pgbenchGsetRegexp matches a '\gset' or '\aset' at the end of an SQL line.
+var pgbenchGsetRegexp = regexp.MustCompile(`\\[ga]set(\s+\w+)?\s*$`)
+
+// pgbenchParser translates a pgbench custom script into a loop benchmark.
+type pgbenchParser struct {
+	stmt      strings.Builder // the translated template
+	stmtLines []scriptLine    // the origin of each line of the template
+	pending   string          // SQL since the last meta-command
+	lastSQL   scriptLine      // the last line of the pending SQL
+	vars      []Var
+	conds     []pgbenchCond // the open '\if' blocks
+	ifs       int           // number of '\if' and '\elif' conditions
+	think     time.Duration
+	errs      ParseErrors
+}
+
+// pgbenchCond is an open '\if' block.
+type pgbenchCond struct {
+	line   scriptLine // the '\if' line
+	inElse bool       // after '\else'
+}
+
+// ParsePgbenchScript parses a pgbench custom script (pgbench -f) and
+// translates it into a single loop benchmark:
+//
+//   - '\set' becomes a variable of the benchmark, ':scale' is 1 unless set
+//     by the script or defined.
+//   - ':name' in SQL statements is replaced with the variable.
+//   - ':client_id' is the 0-based thread number (.ClientID).
+//
+// The defined variables are set before the variables of the script, like
+// 'pgbench -D', see ParseDefine.
+//   - '\if', '\elif', '\else' and '\endif' become template conditions.
+//   - '\sleep' pauses after each iteration (Benchmark.ThinkTime).
+//
+// Shell commands, pipelines, '\gset', '\aset' and the variables ':default_seed'
+// and ':random_seed' are not supported.
+func ParsePgbenchScript(r io.Reader, defines ...Var) (Script, error) {
+	lines, err := readPgbenchLines(r, "")
+	if err != nil {
+		return Script{}, err
+	}
+	name := ""
+	if len(lines) > 0 {
+		name = fmt.Sprintf("line 1-%v", len(lines))
+	}
+	return parsePgbench(lines, name, defines)
+}
+
+// ParsePgbenchScriptFile parses the pgbench custom script of the given file,
+// the benchmark is named after the file. See ParsePgbenchScript for the
+// defined variables.
+func ParsePgbenchScriptFile(path string, defines ...Var) (Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Script{}, err
+	}
+	defer f.Close()
+
+	lines, err := readPgbenchLines(f, path)
+	if err != nil {
+		return Script{}, err
+	}
+	return parsePgbench(lines, filepath.Base(path), defines)
+}
+
+func readPgbenchLines(r io.Reader, file string) ([]scriptLine, error) {
+	lines := []scriptLine{}
+	scanner := bufio.NewScanner(r)
+	for lineN := 1; scanner.Scan(); lineN++ {
+		lines = append(lines, scriptLine{text: scanner.Text(), pos: position{file: file, line: lineN}})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", file, err)
+	}
+	return lines, nil
+}
+
+// parsePgbench translates the lines, name is the name of the benchmark.
+// All errors of the script are returned as ParseErrors.
+func parsePgbench(lines []scriptLine, name string, defines []Var) (Script, error) {
+	p := &pgbenchParser{vars: slices.Clone(defines)}
+
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		line := strings.TrimSpace(l.text)
+
+		// Skip comments and empty lines.
+		if strings.HasPrefix(line, "--") || line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "\\") {
+			p.sql(l)
+			continue
+		}
+
+		// A meta-command ends the pending SQL statement.
+		p.flushSQL()
+
+		// meta-commands continue on the next line after a trailing backslash,
+		// errors point at the first line
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(lines[i].text)
+		}
+		l.text = l.text[:strings.Index(l.text, "\\")] + line
+
+		if err := p.command(l, line); err != nil {
+			p.errs = append(p.errs, errorAt(l, err))
+		}
+	}
+	p.flushSQL()
+
+	for _, c := range p.conds {
+		p.errs = append(p.errs, errorAt(c.line, errors.New("missing \\endif")))
+	}
+
+	stmt := strings.TrimSuffix(p.stmt.String(), "\n")
+	if len(p.errs) == 0 {
+		if len(splitAll(stmt, defaultDelimiter)) == 0 {
+			if len(lines) == 0 || lines[0].pos.file == "" {
+				return Script{}, ErrNoStatements
+			}
+			return Script{}, fmt.Errorf("%v: %w", lines[0].pos.file, ErrNoStatements)
+		}
+		if err := (stmtCheck{stmt: stmt, lines: p.stmtLines, vars: p.vars}).check(); err != nil {
+			p.errs = append(p.errs, err)
+		}
+	}
+	if len(p.errs) > 0 {
+		p.errs.sort()
+		return Script{}, p.errs
+	}
+
+	b := Benchmark{Name: name, Type: TypeLoop, Stmt: stmt, ThinkTime: p.think}
+	if len(p.vars) > 0 {
+		b.Vars = p.vars
+	}
+	b.Name = getName(b, name)
+	return Script{Setup: []string{}, Benchmarks: []Benchmark{b}, Teardown: []string{}}, nil
+}
+
+// write appends a line to the template, l is the line of the script it originates from.
+func (p *pgbenchParser) write(text string, l scriptLine) {
+	p.stmt.WriteString(text + "\n")
+	p.stmtLines = append(p.stmtLines, l)
+}
+
+// flushSQL terminates the pending SQL statement, the last
+// statement before a meta-command may omit the semicolon.
+func (p *pgbenchParser) flushSQL() {
+	if _, rest := splitStatements(p.pending, defaultDelimiter); rest != "" {
+		// on its own line, the statement might end with a comment
+		p.write(defaultDelimiter, p.lastSQL)
+	}
+	p.pending = ""
+}
+
+// sql translates an SQL line of the script.
+func (p *pgbenchParser) sql(l scriptLine) {
+	line := strings.TrimSpace(l.text)
+	if loc := pgbenchGsetRegexp.FindStringIndex(line); loc != nil {
+		cmd := strings.Fields(line[loc[0]:])[0]
+		p.errs = append(p.errs, errorAt(l, &columnError{col: loc[0], err: fmt.Errorf("%v is not supported", cmd)}))
+		return
+	}
+
+	text, err := p.substitute(line)
+	if err != nil {
+		p.errs = append(p.errs, errorAt(l, err))
+		return
+	}
+	p.pending += line + "\n"
+	p.lastSQL = l
+	p.write(text, l)
+}
+
+// substitute replaces the variables of the SQL line with template actions
+// and escapes literal template delimiters. Undefined variables and casts
+// ('::') are left as they are, like pgbench does.
+func (p *pgbenchParser) substitute(line string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(line); {
+		switch {
+		case strings.HasPrefix(line[i:], "{{"):
+			sb.WriteString(`{{"{{"}}`)
+			i += 2
+		case strings.HasPrefix(line[i:], "::"):
+			sb.WriteString("::")
+			i += 2
+		case line[i] == ':':
+			end := i + 1
+			for end < len(line) && isNameChar(line[end], end == i+1) {
+				end++
+			}
+			name := line[i+1 : end]
+			if name == "scale" {
+				p.defineScale()
+			}
+			switch {
+			case p.defined(name):
+				sb.WriteString("{{.Vars." + name + "}}")
+			case name == clientIDVar:
+				sb.WriteString("{{.ClientID}}")
+			case slices.Contains(pgbenchBuiltins, name):
+				return "", &columnError{col: i, err: fmt.Errorf("variable :%v is not supported", name)}
+			default:
+				sb.WriteString(line[i:end])
+			}
+			i = end
+		default:
+			sb.WriteByte(line[i])
+			i++
+		}
+	}
+	return sb.String(), nil
+}
+
+// command translates the trimmed meta-command line of the script.
+func (p *pgbenchParser) command(l scriptLine, line string) error {
+	tokens, cols := fields(line)
+
+	switch cmd := tokens[0]; cmd {
+	case "\\set":
+		if len(p.conds) > 0 {
+			return errors.New("\\set inside of \\if is not supported")
+		}
+		if len(tokens) > 1 && p.defined(tokens[1]) {
+			return &columnError{col: cols[1], err: fmt.Errorf("redefinition of :%v is not supported", tokens[1])}
+		}
+		if len(tokens) > 2 {
+			p.defineScaleIfUsed(line[cols[2]:])
+		}
+		var err error
+		if p.vars, err = defineSet(line, p.vars); err != nil {
+			return err
+		}
+	case "\\sleep":
+		if len(p.conds) > 0 {
+			return errors.New("\\sleep inside of \\if is not supported")
+		}
+		d, err := parseSleep(tokens[1:], cols[1:])
+		if err != nil {
+			return err
+		}
+		p.think += d
+	case "\\if", "\\elif":
+		if cmd == "\\elif" && (len(p.conds) == 0 || p.conds[len(p.conds)-1].inElse) {
+			return errors.New("\\elif without \\if")
+		}
+		if cmd == "\\if" {
+			// open the block also when the condition is invalid, the '\endif' would fail as well otherwise
+			p.conds = append(p.conds, pgbenchCond{line: l})
+		}
+		if len(tokens) < 2 {
+			return fmt.Errorf("missing expression after %v", cmd)
+		}
+
+		expr := line[cols[1]:]
+		p.defineScaleIfUsed(expr)
+		p.ifs++
+		v := Var{Name: fmt.Sprintf("_if%v", p.ifs), Expr: expr}
+		if err := checkExpr(expr, cols[1], p.vars, cmd); err != nil {
+			return err
+		}
+		p.vars = append(p.vars, v)
+
+		if cmd == "\\if" {
+			p.write("{{if .Vars."+v.Name+"}}", l)
+			return nil
+		}
+		p.write("{{else if .Vars."+v.Name+"}}", l)
+	case "\\else", "\\endif":
+		if len(tokens) > 1 {
+			return &columnError{col: cols[1], err: fmt.Errorf("unexpected %v after %v", tokens[1], cmd)}
+		}
+		if len(p.conds) == 0 || cmd == "\\else" && p.conds[len(p.conds)-1].inElse {
+			return fmt.Errorf("%v without \\if", cmd)
+		}
+		if cmd == "\\else" {
+			p.conds[len(p.conds)-1].inElse = true
+			p.write("{{else}}", l)
+			return nil
+		}
+		p.conds = p.conds[:len(p.conds)-1]
+		p.write("{{end}}", l)
+	default:
+		if slices.Contains(pgbenchUnsupported, cmd) {
+			return fmt.Errorf("%v is not supported", cmd)
+		}
+		return fmt.Errorf("unknown command %v", cmd)
+	}
+	return nil
+}
+
+// defined reports whether the variable is set by the script.
+func (p *pgbenchParser) defined(name string) bool {
+	return slices.ContainsFunc(p.vars, func(v Var) bool { return v.Name == name })
+}
+
+// defineScale sets ':scale' to 1 when the script didn't set it,
+// pgbench does the same for custom scripts without '--scale'.
+func (p *pgbenchParser) defineScale() {
+	if !p.defined("scale") {
+		p.vars = append(p.vars, Var{Name: "scale", Expr: "1"})
+	}
+}
+
+// defineScaleIfUsed defines ':scale' when the expression references it.
+func (p *pgbenchParser) defineScaleIfUsed(expr string) {
+	if _, refs, err := parseExpr(expr); err == nil && slices.Contains(refs, "scale") {
+		p.defineScale()
+	}
+}
+
+// parseSleep parses the arguments of '\sleep number [us|ms|s]',
+// cols are the columns of the tokens.
+func parseSleep(tokens []string, cols []int) (time.Duration, error) {
+	if len(tokens) == 0 {
+		return 0, errors.New("missing duration after \\sleep")
+	}
+	if strings.HasPrefix(tokens[0], ":") {
+		return 0, &columnError{col: cols[0], err: errors.New("\\sleep with a variable is not supported")}
+	}
+	n, err := strconv.Atoi(tokens[0])
+	if err == nil && n < 0 {
+		err = fmt.Errorf("%v is negative", n)
+	}
+	if err != nil {
+		return 0, &columnError{col: cols[0], err: fmt.Errorf("failed to parse \\sleep: %v", err)}
+	}
+
+	unit := time.Second
+	if len(tokens) > 1 {
+		switch tokens[1] {
+		case "us":
+			unit = time.Microsecond
+		case "ms":
+			unit = time.Millisecond
+		case "s":
+		default:
+			return 0, &columnError{col: cols[1], err: fmt.Errorf("unknown unit %v after \\sleep, neither 'us', 'ms' nor 's'", tokens[1])}
+		}
+	}
+	if len(tokens) > 2 {
+		return 0, &columnError{col: cols[2], err: fmt.Errorf("unexpected %v after \\sleep", tokens[2])}
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,216 @@
+package benchmark
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePgbenchScript(t *testing.T) {
+	// the built-in TPC-B like script of pgbench
+	in := `\set aid random(1, 100000 * :scale)
+\set bid random(1, 1 * :scale)
+\set tid random(1, 10 * :scale)
+\set delta random(-5000, 5000)
+BEGIN;
+UPDATE pgbench_accounts SET abalance = abalance + :delta WHERE aid = :aid;
+SELECT abalance FROM pgbench_accounts WHERE aid = :aid;
+INSERT INTO pgbench_history (tid, bid, aid, delta, mtime)
+  VALUES (:tid, :bid, :aid, :delta, CURRENT_TIMESTAMP);
+\sleep 10 ms
+END;
+`
+	got, err := ParsePgbenchScript(strings.NewReader(in))
+	require.NoError(t, err)
+
+	require.Equal(t, Script{
+		Setup:    []string{},
+		Teardown: []string{},
+		Benchmarks: []Benchmark{{
+			Name: "(loop) line 1-11",
+			Type: TypeLoop,
+			Stmt: `BEGIN;
+UPDATE pgbench_accounts SET abalance = abalance + {{.Vars.delta}} WHERE aid = {{.Vars.aid}};
+SELECT abalance FROM pgbench_accounts WHERE aid = {{.Vars.aid}};
+INSERT INTO pgbench_history (tid, bid, aid, delta, mtime)
+VALUES ({{.Vars.tid}}, {{.Vars.bid}}, {{.Vars.aid}}, {{.Vars.delta}}, CURRENT_TIMESTAMP);
+END;`,
+			Vars: []Var{
+				{Name: "scale", Expr: "1"},
+				{Name: "aid", Expr: "random(1, 100000 * :scale)"},
+				{Name: "bid", Expr: "random(1, 1 * :scale)"},
+				{Name: "tid", Expr: "random(1, 10 * :scale)"},
+				{Name: "delta", Expr: "random(-5000, 5000)"},
+			},
+			ThinkTime: 10 * time.Millisecond,
+		}},
+	}, got)
+}
+
+func TestParsePgbenchScriptTranslation(t *testing.T) {
+	testCases := []struct {
+		description string
+		in          string
+		stmt        string
+		vars        []Var
+		think       time.Duration
+	}{
+		{
+			description: "casts and undefined variables",
+			in:          "\\set id 1\nSELECT :id::text, ':other', '{{x}}'",
+			stmt:        "SELECT {{.Vars.id}}::text, ':other', '{{\"{{\"}}x}}'\n;",
+			vars:        []Var{{Name: "id", Expr: "1"}},
+		},
+		{
+			description: "scale set by the script",
+			in:          "\\set scale 10\nSELECT :scale;",
+			stmt:        "SELECT {{.Vars.scale}};",
+			vars:        []Var{{Name: "scale", Expr: "10"}},
+		},
+		{
+			description: "client id",
+			in:          "SELECT :client_id;",
+			stmt:        "SELECT {{.ClientID}};",
+		},
+		{
+			description: "client id in expressions",
+			in:          "\\set part :client_id % 4\nSELECT :part;",
+			stmt:        "SELECT {{.Vars.part}};",
+			vars:        []Var{{Name: "part", Expr: ":client_id % 4"}},
+		},
+		{
+			description: "missing semicolon before a command",
+			in:          "SELECT 1 -- one\n\\sleep 1\nSELECT 2\n\\sleep 500 us\n\\sleep 2 s",
+			stmt:        "SELECT 1 -- one\n;\nSELECT 2\n;",
+			think:       3*time.Second + 500*time.Microsecond,
+		},
+		{
+			description: "conditions",
+			in: `\set r random(1, 100)
+\if :r <= 60 AND :scale > 0
+SELECT 1;
+\elif :r <= 90
+SELECT 2;
+\else
+SELECT 3;
+\endif`,
+			stmt: "{{if .Vars._if1}}\nSELECT 1;\n{{else if .Vars._if2}}\nSELECT 2;\n{{else}}\nSELECT 3;\n{{end}}",
+			vars: []Var{
+				{Name: "r", Expr: "random(1, 100)"},
+				{Name: "scale", Expr: "1"},
+				{Name: "_if1", Expr: ":r <= 60 AND :scale > 0"},
+				{Name: "_if2", Expr: ":r <= 90"},
+			},
+		},
+		{
+			description: "continued command",
+			in:          "\\set id 1 + \\\n  2\nSELECT :id;",
+			stmt:        "SELECT {{.Vars.id}};",
+			vars:        []Var{{Name: "id", Expr: "1 +  2"}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.description, func(t *testing.T) {
+			got, err := ParsePgbenchScript(strings.NewReader(tt.in))
+			require.NoError(t, err)
+			require.Len(t, got.Benchmarks, 1)
+			require.Equal(t, tt.stmt, got.Benchmarks[0].Stmt)
+			require.Equal(t, tt.vars, got.Benchmarks[0].Vars)
+			require.Equal(t, tt.think, got.Benchmarks[0].ThinkTime)
+		})
+	}
+}
+
+func TestParsePgbenchScriptConditions(t *testing.T) {
+	got, err := ParsePgbenchScript(strings.NewReader("\\set r 2\n\\if :r = 1\nSELECT 1;\n\\elif :r = 2\nSELECT 2;\n\\else\nSELECT 3;\n\\endif\nSELECT 4;"))
+	require.NoError(t, err)
+
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+	Run(context.Background(), bencher, got.Benchmarks[0], Options{Iter: 1, Threads: 1})
+
+	bencher.AssertNumberOfCalls(t, "Exec", 2)
+	bencher.AssertCalled(t, "Exec", "SELECT 2")
+	bencher.AssertCalled(t, "Exec", "SELECT 4")
+}
+
+func TestParsePgbenchScriptClientID(t *testing.T) {
+	got, err := ParsePgbenchScript(strings.NewReader("\\set id :client_id + 10\nSELECT :id;"))
+	require.NoError(t, err)
+
+	bencher := &mockedBencher{}
+	bencher.On("Exec", mock.Anything).Return(nil)
+	Run(context.Background(), bencher, got.Benchmarks[0], Options{Iter: 1, Threads: 1})
+
+	bencher.AssertNumberOfCalls(t, "Exec", 1)
+	bencher.AssertCalled(t, "Exec", "SELECT 10")
+}
+
+func TestParsePgbenchScriptDefines(t *testing.T) {
+	in := "\\set aid random(1, 100000 * :scale)\nSELECT :aid, :branches;"
+	got, err := ParsePgbenchScript(strings.NewReader(in), Var{Name: "scale", Expr: "10"}, Var{Name: "branches", Expr: "2"})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT {{.Vars.aid}}, {{.Vars.branches}};", got.Benchmarks[0].Stmt)
+	require.Equal(t, []Var{
+		{Name: "scale", Expr: "10"},
+		{Name: "branches", Expr: "2"},
+		{Name: "aid", Expr: "random(1, 100000 * :scale)"},
+	}, got.Benchmarks[0].Vars)
+
+	_, err = ParsePgbenchScript(strings.NewReader("\\set scale 5\nSELECT :scale;"), Var{Name: "scale", Expr: "10"})
+	require.EqualError(t, err, "line 1:6: redefinition of :scale is not supported")
+}
+
+func TestParsePgbenchScriptErrors(t *testing.T) {
+	in := `\set aid random(1, :missing)
+  \shell echo hi
+SELECT :random_seed;
+SELECT 1 \gset
+\set aid 1
+\sleep :duration ms
+\sleep 1 min
+\endif
+\if :aid = 1
+\set inner 1
+\else
+\elif true
+\unknown
+`
+	_, err := ParsePgbenchScript(strings.NewReader(in))
+	require.EqualError(t, err, strings.Join([]string{
+		"line 1:20: undefined variable :missing in \\set aid",
+		"line 2:3: \\shell is not supported",
+		"line 3:8: variable :random_seed is not supported",
+		"line 4:10: \\gset is not supported",
+		"line 5:6: redefinition of :aid is not supported",
+		"line 6:8: \\sleep with a variable is not supported",
+		"line 7:10: unknown unit min after \\sleep, neither 'us', 'ms' nor 's'",
+		"line 8:1: \\endif without \\if",
+		"line 9:1: missing \\endif",
+		"line 10:1: \\set inside of \\if is not supported",
+		"line 12:1: \\elif without \\if",
+		"line 13:1: unknown command \\unknown",
+	}, "\n"))
+
+	_, err = ParsePgbenchScript(strings.NewReader("\\set id 1\n\\sleep 1\n"))
+	require.ErrorIs(t, err, ErrNoStatements)
+}
+
+func TestParsePgbenchScriptFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"select-only.sql": "\\set aid random(1, 100000 * :scale)\nSELECT abalance FROM pgbench_accounts WHERE aid = :aid;\n"})
+
+	got, err := ParsePgbenchScriptFile(filepath.Join(dir, "select-only.sql"))
+	require.NoError(t, err)
+	require.Equal(t, "(loop) select-only.sql", got.Benchmarks[0].Name)
+
+	writeFiles(t, dir, map[string]string{"invalid.sql": "SELECT 1;\n\\if\n\\endif\n"})
+	_, err = ParsePgbenchScriptFile(filepath.Join(dir, "invalid.sql"))
+	require.EqualError(t, err, filepath.Join(dir, "invalid.sql")+":2:1: missing expression after \\if")
+}
@@ -0,0 +1,135 @@
+package benchmark
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// defaultDelimiter separates the statements of a script, unless
+// changed with '\delimiter'.
+const defaultDelimiter = ";"
+
+// splitStatements splits the text at the delimiter into its statements.
+// Delimiters in quoted strings and identifiers ('...', "...", `...`),
+// dollar-quoted strings ($$...$$, $tag$...$tag$), comments (--, /* */) and
+// template actions ({{...}}) don't end a statement. The statements are
+// returned without the delimiter, statements which only consist of comments
+// are skipped. rest is the incomplete statement after the last delimiter.
+func splitStatements(text, delim string) (stmts []string, rest string) {
+	if delim == "" {
+		delim = defaultDelimiter
+	}
+
+	stmts = []string{}
+	start := 0       // start of the current statement
+	content := false // the current statement contains more than whitespace and comments
+
+	for i := 0; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], delim):
+			// checked first, e.g. '$$' is a common delimiter for procedures in MySQL
+			if content {
+				stmts = append(stmts, strings.TrimSpace(text[start:i]))
+			}
+			i += len(delim)
+			start, content = i, false
+		case strings.HasPrefix(text[i:], "{{"):
+			i, content = skipPast(text, i+2, "}}"), true
+		case strings.HasPrefix(text[i:], "--"):
+			i = skipPast(text, i+2, "\n")
+		case strings.HasPrefix(text[i:], "/*"):
+			i = skipPast(text, i+2, "*/")
+		case text[i] == '\'' || text[i] == '"' || text[i] == '`':
+			// doubled quotes for escaping are two consecutive quoted strings
+			i, content = skipPast(text, i+1, text[i:i+1]), true
+		case text[i] == '$':
+			tag, ok := dollarTag(text[i:])
+			if ok && (i == 0 || !isIdentChar(text[i-1])) {
+				i = skipPast(text, i+len(tag), tag)
+			} else {
+				i++
+			}
+			content = true
+		default:
+			if !unicode.IsSpace(rune(text[i])) {
+				content = true
+			}
+			i++
+		}
+	}
+
+	if content {
+		rest = strings.TrimSpace(text[start:])
+	}
+	return stmts, rest
+}
+
+// splitAll returns all statements of the text, including
+// the last one without a delimiter.
+func splitAll(text, delim string) []string {
+	stmts, rest := splitStatements(text, delim)
+	if rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
+
+// skipPast returns the index after the next occurrence of end, starting at i,
+// or the length of the text when there is none.
+func skipPast(text string, i int, end string) int {
+	n := strings.Index(text[i:], end)
+	if n < 0 {
+		return len(text)
+	}
+	return i + n + len(end)
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string at the
+// start of s, e.g. '$$' or '$body$'. Parameters like '$1' are no tags.
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1], true
+		case !isIdentChar(s[i]), i == 1 && s[i] >= '0' && s[i] <= '9':
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// isIdentChar reports whether the byte can be part of an unquoted identifier.
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// stmtKeywords are the first words of common statements, lines continuing
+// a statement rarely start with them.
+var stmtKeywords = []string{"ALTER", "BEGIN", "COMMIT", "CREATE", "DELETE", "DROP", "INSERT", "REPLACE", "ROLLBACK", "SELECT", "TRUNCATE", "UPDATE", "UPSERT"}
+
+// looksComplete reports whether the line looks like a statement of its own,
+// it starts with a statement keyword and closes its parentheses and quotes.
+func looksComplete(line string) bool {
+	word := strings.FieldsFunc(line, func(r rune) bool { return !unicode.IsLetter(r) })
+	if len(word) == 0 || !strings.HasPrefix(line, word[0]) || !slices.Contains(stmtKeywords, strings.ToUpper(word[0])) {
+		return false
+	}
+
+	depth, quote := 0, rune(0)
+	for _, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		}
+	}
+	return depth == 0 && quote == 0
+}
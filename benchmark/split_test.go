@@ -0,0 +1,95 @@
+package benchmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	testCases := []struct {
+		description string
+		in          string
+		delim       string
+		stmts       []string
+		rest        string
+	}{
+		{
+			description: "single statement",
+			in:          "SELECT 1;",
+			stmts:       []string{"SELECT 1"},
+		},
+		{
+			description: "several statements",
+			in:          "BEGIN;\nINSERT INTO t VALUES (1);\nCOMMIT;",
+			stmts:       []string{"BEGIN", "INSERT INTO t VALUES (1)", "COMMIT"},
+		},
+		{
+			description: "rest",
+			in:          "SELECT 1; SELECT\n2",
+			stmts:       []string{"SELECT 1"},
+			rest:        "SELECT\n2",
+		},
+		{
+			description: "empty statements and comments",
+			in:          ";; SELECT 1; -- comment\n /* comment; */ ;",
+			stmts:       []string{"SELECT 1"},
+		},
+		{
+			description: "comments in statement",
+			in:          "SELECT 1 -- one;\n, 2 /* two; */;",
+			stmts:       []string{"SELECT 1 -- one;\n, 2 /* two; */"},
+		},
+		{
+			description: "quotes",
+			in:          `SELECT 'a;b', 'it''s;', "c;d", ` + "`e;f`;SELECT 2;",
+			stmts:       []string{`SELECT 'a;b', 'it''s;', "c;d", ` + "`e;f`", "SELECT 2"},
+		},
+		{
+			description: "dollar quotes",
+			in:          "CREATE FUNCTION f() AS $$ BEGIN; END; $$;\nDO $body$ SELECT '$$;'; $body$;",
+			stmts:       []string{"CREATE FUNCTION f() AS $$ BEGIN; END; $$", "DO $body$ SELECT '$$;'; $body$"},
+		},
+		{
+			description: "parameters",
+			in:          "SELECT $1; SELECT a$b$c;",
+			stmts:       []string{"SELECT $1", "SELECT a$b$c"},
+		},
+		{
+			description: "template actions",
+			in:          `SELECT '{{.Iter}}', {{printf "%v;" .Iter}};`,
+			stmts:       []string{`SELECT '{{.Iter}}', {{printf "%v;" .Iter}}`},
+		},
+		{
+			description: "custom delimiter",
+			in:          "CREATE PROCEDURE p() BEGIN SELECT 1; END //\nCALL p()//",
+			delim:       "//",
+			stmts:       []string{"CREATE PROCEDURE p() BEGIN SELECT 1; END", "CALL p()"},
+		},
+		{
+			description: "dollar delimiter",
+			in:          "CREATE PROCEDURE p() BEGIN SELECT 1; END $$",
+			delim:       "$$",
+			stmts:       []string{"CREATE PROCEDURE p() BEGIN SELECT 1; END"},
+		},
+		{
+			description: "unterminated quote",
+			in:          "SELECT 'a;",
+			stmts:       []string{},
+			rest:        "SELECT 'a;",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.description, func(t *testing.T) {
+			stmts, rest := splitStatements(tt.in, tt.delim)
+			require.Equal(t, tt.stmts, stmts)
+			require.Equal(t, tt.rest, rest)
+		})
+	}
+}
+
+func TestSplitAll(t *testing.T) {
+	require.Equal(t, []string{"SELECT 1", "SELECT 2"}, splitAll("SELECT 1;\nSELECT 2", ";"))
+	require.Equal(t, []string{}, splitAll(" -- comment", ";"))
+}
@@ -0,0 +1,165 @@
+package benchmark
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseError is returned when a script can't be parsed.
+// It points at the line and column (1-based) in the original file which caused the error.
+type ParseError struct {
+	File string // empty when the script wasn't read from a file
+	Line int
+	Col  int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %v:%v: %v", e.Line, e.Col, e.Err)
+	}
+	return fmt.Sprintf("%v:%v:%v: %v", e.File, e.Line, e.Col, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors contains all errors of a script, ordered by file, line and column.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// sort orders the errors by their file, line and column.
+func (e ParseErrors) sort() {
+	slices.SortStableFunc(e, func(a, b *ParseError) int {
+		return cmp.Or(strings.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Col, b.Col))
+	})
+}
+
+func (e ParseErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// columnError is an error at the column (0-based) of the trimmed line.
+type columnError struct {
+	col int
+	err error
+}
+
+func (e *columnError) Error() string {
+	return e.err.Error()
+}
+
+// errorAt returns a parse error pointing at the line. Without a columnError,
+// it points at the first non-space character of the line.
+func errorAt(l scriptLine, err error) *ParseError {
+	indent := len(l.text) - len(strings.TrimLeftFunc(l.text, unicode.IsSpace))
+
+	col := 0
+	var colErr *columnError
+	if errors.As(err, &colErr) {
+		col, err = colErr.col, colErr.err
+	}
+	return &ParseError{File: l.pos.file, Line: l.pos.line, Col: indent + col + 1, Err: err}
+}
+
+// fields splits the line around whitespace like strings.Fields
+// and returns the column (0-based) of each field as well.
+func fields(line string) ([]string, []int) {
+	tokens, cols := []string{}, []int{}
+	start := -1
+	for i, r := range line + " " {
+		switch {
+		case unicode.IsSpace(r) && start >= 0:
+			tokens = append(tokens, line[start:i])
+			cols = append(cols, start)
+			start = -1
+		case !unicode.IsSpace(r) && start < 0:
+			start = i
+		}
+	}
+	return tokens, cols
+}
+
+// stmtCheck is a statement of the script which is checked after parsing.
+type stmtCheck struct {
+	stmt  string
+	lines []scriptLine // the lines of the statement, one for each line of the template
+	vars  []Var
+	setup bool // setup or teardown statement, which doesn't support bind parameters
+}
+
+// templateErrRegexp matches the location and the message of template errors,
+// e.g. 'template: stmt:2:11: executing "stmt" at <.Foo>: can't evaluate field Foo'.
+var templateErrRegexp = regexp.MustCompile(`^template: stmt:(\d+):(?:(\d+):)? (?:executing "stmt" at )?(.*)$`)
+
+// templateTypeRegexp matches the type of the template data in errors of unknown fields,
+// it lists all fields and functions and is too long to be helpful.
+var templateTypeRegexp = regexp.MustCompile(` in type struct \{.*\}$`)
+
+// check compiles the statement and renders it once.
+// Errors point at the line of the statement which caused it.
+func (c stmtCheck) check() *ParseError {
+	compiled, err := compileVars(c.vars)
+	if err != nil {
+		return errorAt(c.lines[0], err)
+	}
+	it := firstIteration(newRand(0, 1))
+	vars, err := evalVars(compiled, it)
+	if err != nil {
+		return errorAt(c.lines[0], err)
+	}
+
+	t, err := parseTemplate("stmt", c.stmt)
+	if err != nil {
+		return c.templateError(err)
+	}
+
+	// dry-render the first iteration to find execution errors, e.g. misspelled fields
+	rendered, args, err := renderStmt(t, it, vars)
+	if err != nil {
+		return c.templateError(err)
+	}
+	if c.setup && len(args) > 0 {
+		// the rendered line of the first parameter, usually the line of the template
+		line := strings.Count(rendered[:max(strings.Index(rendered, argMarker(0)), 0)], "\n")
+		return errorAt(c.lines[min(line, len(c.lines)-1)], errArgsNotInBenchmark)
+	}
+	return nil
+}
+
+// templateError converts the template error to a parse error
+// pointing at the line and column of the script.
+func (c stmtCheck) templateError(err error) *ParseError {
+	m := templateErrRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return errorAt(c.lines[0], err)
+	}
+
+	line, _ := strconv.Atoi(m[1])
+	line = min(max(line, 1), len(c.lines))
+
+	col := 0
+	if m[2] != "" {
+		col, _ = strconv.Atoi(m[2])
+	}
+	msg := templateTypeRegexp.ReplaceAllString(m[3], "")
+	return errorAt(c.lines[line-1], &columnError{col: col, err: errors.New(msg)})
+}
@@ -0,0 +1,102 @@
+package benchmark
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScriptErrorPositions(t *testing.T) {
+	testCases := []struct {
+		description string
+		in          string
+		expect      string
+	}{
+		{
+			description: "unknown mode",
+			in:          "  \\benchmark unknown-mode",
+			expect:      "line 1:14: failed to parse mode, neither 'once' nor 'loop': unknown-mode",
+		},
+		{
+			description: "invalid option value",
+			in:          "\\benchmark loop \\iter 0\nSELECT 1;",
+			expect:      "line 1:23: failed to parse \\iter: 0 is not greater than 0",
+		},
+		{
+			description: "invalid mix weight",
+			in:          "\\mix x\nSELECT 1;",
+			expect:      "line 1:6: failed to parse \\mix weight: strconv.Atoi: parsing \"x\": invalid syntax",
+		},
+		{
+			description: "invalid expression",
+			in:          "\\set aid random(1, 10\nSELECT {{.Vars.aid}};",
+			expect:      "line 1:22: failed to parse \\set aid: missing ')' at position 13",
+		},
+		{
+			description: "undefined variable",
+			in:          "\\set aid 1 + :scale\nSELECT {{.Vars.aid}};",
+			expect:      "line 1:14: undefined variable :scale in \\set aid",
+		},
+		{
+			description: "template parse error",
+			in:          "\\benchmark loop\nSELECT 1;\nSELECT {{.Iter};",
+			expect:      "line 3:1: bad character U+007D '}'",
+		},
+		{
+			description: "template exec error",
+			in:          "\\benchmark once\n  SELECT {{.Vars.aid}};",
+			expect:      "line 2:17: <.Vars.aid>: map has no entry for key \"aid\"",
+		},
+		{
+			description: "unknown field",
+			in:          "SELECT {{.Itr}};",
+			expect:      "line 1:10: <.Itr>: can't evaluate field Itr",
+		},
+		{
+			description: "undefined template variable",
+			in:          "\\set aid 1\nSELECT {{.Vars.bid}};",
+			expect:      "line 2:15: <.Vars.bid>: map has no entry for key \"bid\"",
+		},
+		{
+			description: "setup statement",
+			in:          "\\setup\nINSERT INTO t VALUES ({{call .RandInt64N}});",
+			expect:      "line 2:25: <call .RandInt64N>: error calling call: wrong number of args for .RandInt64N: got 0 want 1",
+		},
+		{
+			description: "function error",
+			in:          "SELECT '{{call .RandTime \"2024-12-31\" \"2024-01-01\"}}';",
+			expect:      "line 1:11: <call .RandTime \"2024-12-31\" \"2024-01-01\">: error calling call: RandTime: 2024-01-01 is not after 2024-12-31",
+		},
+		{
+			description: "bind parameter in setup",
+			in:          "\\setup\nINSERT INTO t\nVALUES ({{call .Arg 2}});",
+			expect:      "line 3:1: bind parameters are only supported in benchmarks",
+		},
+		{
+			description: "multiple errors",
+			in:          "\\benchmark once \\name\nSELECT 1;\n\\benchmark loop\nSELECT {{.Vars.aid}};\n\\mix 1",
+			expect: "line 1:17: missing name after \\name token\n" +
+				"line 4:15: <.Vars.aid>: map has no entry for key \"aid\"\n" +
+				"line 5:1: statements of a loop benchmark with \\mix have to follow a \\mix line",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.description, func(t *testing.T) {
+			got, err := ParseScript(strings.NewReader(tt.in))
+			require.EqualError(t, err, tt.expect)
+			require.Equal(t, Script{}, got)
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	tokens, cols := fields("\\benchmark  loop\t\\name x ")
+	require.Equal(t, []string{"\\benchmark", "loop", "\\name", "x"}, tokens)
+	require.Equal(t, []int{0, 12, 17, 23}, cols)
+
+	tokens, cols = fields("")
+	require.Empty(t, tokens)
+	require.Empty(t, cols)
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"math/rand/v2"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/sj14/dbbench/benchmark"
 	"github.com/sj14/dbbench/databases"
+	"github.com/sj14/dbbench/report"
 	"github.com/spf13/pflag"
 	_ "modernc.org/sqlite"
 )
@@ -30,6 +34,13 @@ func main() {
 		defaultFlags = pflag.NewFlagSet("defaults", pflag.ExitOnError)
 		iter         = defaultFlags.Int("iter", 1000, "how many iterations should be run")
 		threads      = defaultFlags.Int("threads", 25, "max. number of green threads (iter >= threads > 0)")
+		duration     = defaultFlags.Duration("duration", 0, "run each loop benchmark for the given duration instead of --iter iterations (valid units: ns, us, ms, s, m, h)")
+		rate         = defaultFlags.Float64("rate", 0, "target throughput of loop benchmarks in ops/s, latency is measured from the scheduled start (0 = unlimited)")
+		stmtTimeout  = defaultFlags.Duration("stmt-timeout", 0, "abort statements which take longer and count them as errors (0 = no timeout)")
+		warmup       = defaultFlags.String("warmup", "", "run each loop benchmark for the given iterations (e.g. 500) or duration (e.g. 30s) before measuring")
+		interval     = defaultFlags.Duration("report-interval", 0, "print the metrics of loop benchmarks periodically to stderr and add them to the results (e.g. 1s, 0 = disabled)")
+		seed         = defaultFlags.Uint64("seed", 0, "seed of the random values of the statements, each thread derives its own generator from it (0 = random seed)")
+		runID        = defaultFlags.String("run-id", "", "identifies the run in the statements as {{.RunID}} (default: a new UUIDv7)")
 		sleep        = defaultFlags.Duration("sleep", 0, "how long to pause after each single benchmark (valid units: ns, us, ms, s, m, h)")
 		nosetup      = defaultFlags.Bool("noinit", false, "do not initialize database and tables, e.g. when only running own script")
 		clean        = defaultFlags.Bool("clean", false, "only cleanup benchmark data, e.g. after a crash")
@@ -37,6 +48,14 @@ func main() {
 		versionFlag  = defaultFlags.Bool("version", false, "print version information")
 		runBench     = defaultFlags.String("run", "all", "only run the specified benchmarks, e.g. \"inserts deletes\"")
 		scriptname   = defaultFlags.String("script", "", "custom sql file to execute")
+		scriptFormat = defaultFlags.String("script-format", "dbbench", "format of the --script: dbbench|pgbench (pgbench custom script)")
+		defines      = defaultFlags.StringArray("define", nil, "set the variable of the --script to the expression, e.g. scale=10 (repeatable, like pgbench -D)")
+		format       = defaultFlags.String("format", "text", "output format: "+strings.Join(report.Formats, "|"))
+		outputFile   = defaultFlags.String("output", "", "write the results to the given file instead of stdout")
+		baseline     = defaultFlags.String("baseline", "", "compare the results with the given JSON results and exit with 1 on regressions")
+		maxRegress   = defaultFlags.String("max-regression", "10%", "max. allowed regression of ops/s and latency percentiles, used with --baseline and compare")
+		dryRun       = defaultFlags.Bool("dry-run", false, "print the statements of the script or the built-in benchmarks instead of executing them, without connecting to the database")
+		dryRunIter   = defaultFlags.Int("dry-run-iter", 10, "max. iterations of each loop benchmark to print with --dry-run")
 
 		// Connection flags, applicable for most databases (not sqlite).
 		connFlags = pflag.NewFlagSet("conn", pflag.ExitOnError)
@@ -49,6 +68,10 @@ func main() {
 		maxconnsFlags = pflag.NewFlagSet("conns", pflag.ExitOnError)
 		maxconns      = maxconnsFlags.Int("conns", 0, "max. number of open connections")
 
+		// Prepared statements, applicable for the database/sql drivers (not cassandra, spanner).
+		prepareFlags = pflag.NewFlagSet("prepare", pflag.ExitOnError)
+		prepare      = prepareFlags.Bool("prepare", false, "prepare each statement with bind parameters once per connection and execute it with their arguments")
+
 		// GCP specific application flags (for Spanner)
 		gcpFlags        = pflag.NewFlagSet("gcp", pflag.ExitOnError)
 		instanceID      = gcpFlags.String("instance", "", "ID of the Spanner instance")
@@ -64,10 +87,18 @@ func main() {
 		postgresFlags  = pflag.NewFlagSet("postgres", pflag.ExitOnError)
 		sqliteFlags    = pflag.NewFlagSet("sqlite", pflag.ExitOnError)
 		spannerFlags   = pflag.NewFlagSet("spanner", pflag.ExitOnError)
+
+		// Flags for comparing results.
+		compareFlags = pflag.NewFlagSet("compare", pflag.ExitOnError)
+
+		// Flags for validating scripts.
+		validateFlags = pflag.NewFlagSet("validate", pflag.ExitOnError)
 	)
 
 	defaultFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Available subcommands:\n\tcassandra|cockroach|mssql|mysql|postgres|sqlite|spanner\n")
+		fmt.Fprintf(os.Stderr, "\tcompare old.json new.json\n")
+		fmt.Fprintf(os.Stderr, "\tvalidate script.sql\n")
 		fmt.Fprintf(os.Stderr, "\tUse 'subcommand --help' for all flags of the specified command.\n")
 		fmt.Fprintf(os.Stderr, "Generic flags for all subcommands:\n")
 		defaultFlags.PrintDefaults()
@@ -79,54 +110,122 @@ func main() {
 		os.Exit(1)
 	}
 
-	var bencher benchmark.Bencher
+	// the bencher connects to the database, which is not required for a dry-run
+	var newBencher func() benchmark.Bencher
+	// the built-in benchmarks of the database for a dry-run, nil when there are none
+	var builtins func() []benchmark.Benchmark
+	// the placeholder of the bind parameters, nil uses '?'
+	var placeholder func(n int) string
 	switch os.Args[1] {
+	case "compare":
+		compareFlags.AddFlag(defaultFlags.Lookup("max-regression"))
+		if err := compareFlags.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("failed to parse compare flags: %v", err)
+		}
+		if compareFlags.NArg() != 2 {
+			fmt.Fprintf(os.Stderr, "usage: dbbench compare old.json new.json [--max-regression 10%%]\n")
+			os.Exit(1)
+		}
+		oldReport, err := report.LoadFile(compareFlags.Arg(0))
+		if err != nil {
+			log.Fatalf("failed to load results: %v", err)
+		}
+		newReport, err := report.LoadFile(compareFlags.Arg(1))
+		if err != nil {
+			log.Fatalf("failed to load results: %v", err)
+		}
+		maxRegression, err := report.ParsePercent(*maxRegress)
+		if err != nil {
+			log.Fatalf("failed to parse max. regression: %v", err)
+		}
+		if compareReports(os.Stdout, oldReport, newReport, maxRegression) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case "validate":
+		validateFlags.AddFlag(defaultFlags.Lookup("script-format"))
+		validateFlags.AddFlag(defaultFlags.Lookup("define"))
+		if err := validateFlags.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("failed to parse validate flags: %v", err)
+		}
+		if validateFlags.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "usage: dbbench validate script.sql [--script-format dbbench|pgbench] [--define name=expr]\n")
+			os.Exit(1)
+		}
+		script, err := parseScriptFile(validateFlags.Arg(0), *scriptFormat, *defines)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, w := range script.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", w)
+		}
+		fmt.Printf("%v: ok, %v benchmarks, %v setup and %v teardown statements\n",
+			validateFlags.Arg(0), len(script.Benchmarks), len(script.Setup), len(script.Teardown))
+		os.Exit(0)
 	case "postgres":
 		postgresFlags.AddFlagSet(defaultFlags)
 		postgresFlags.AddFlagSet(connFlags)
 		postgresFlags.AddFlagSet(maxconnsFlags)
+		postgresFlags.AddFlagSet(prepareFlags)
 		if err := postgresFlags.Parse(os.Args[2:]); err != nil {
 			log.Fatalf("failed to parse postgres flags: %v", err)
 		}
-		bencher = databases.NewPostgres(*host, *port, *user, *pass, *maxconns)
+		placeholder = databases.DollarPlaceholder
+		newBencher = func() benchmark.Bencher {
+			return databases.NewPostgres(*host, *port, *user, *pass, *maxconns, *prepare)
+		}
+		builtins = databases.PostgresBenchmarks
 	case "cockroach":
 		cockroachFlags.AddFlagSet(defaultFlags)
 		cockroachFlags.AddFlagSet(connFlags)
 		cockroachFlags.AddFlagSet(maxconnsFlags)
+		cockroachFlags.AddFlagSet(prepareFlags)
 		if err := cockroachFlags.Parse(os.Args[2:]); err != nil {
 			log.Fatalf("failed to parse cockroach flags: %v", err)
 		}
-		bencher = databases.NewCockroach(*host, *port, *user, *pass, *maxconns)
+		placeholder = databases.DollarPlaceholder
+		newBencher = func() benchmark.Bencher {
+			return databases.NewCockroach(*host, *port, *user, *pass, *maxconns, *prepare)
+		}
+		builtins = databases.CockroachBenchmarks
 	case "cassandra", "scylla":
 		cassandraFlags.AddFlagSet(defaultFlags)
 		cassandraFlags.AddFlagSet(connFlags)
 		if err := cassandraFlags.Parse(os.Args[2:]); err != nil {
 			log.Fatalf("failed to parse cassandra flags: %v", err)
 		}
-		bencher = databases.NewCassandra(*host, *port, *user, *pass)
+		newBencher = func() benchmark.Bencher { return databases.NewCassandra(*host, *port, *user, *pass) }
+		builtins = databases.CassandraBenchmarks
 	case "mysql", "mariadb", "tidb":
 		mysqlFlags.AddFlagSet(defaultFlags)
 		mysqlFlags.AddFlagSet(connFlags)
 		mysqlFlags.AddFlagSet(maxconnsFlags)
+		mysqlFlags.AddFlagSet(prepareFlags)
 		if err := mysqlFlags.Parse(os.Args[2:]); err != nil {
 			log.Fatalf("failed to parse mysql flags: %v", err)
 		}
-		bencher = databases.NewMySQL(*host, *port, *user, *pass, *maxconns)
+		newBencher = func() benchmark.Bencher { return databases.NewMySQL(*host, *port, *user, *pass, *maxconns, *prepare) }
+		builtins = databases.MySQLBenchmarks
 	case "mssql":
 		mssqlFlags.AddFlagSet(defaultFlags)
 		mssqlFlags.AddFlagSet(connFlags)
 		mssqlFlags.AddFlagSet(maxconnsFlags)
+		mssqlFlags.AddFlagSet(prepareFlags)
 		if err := mssqlFlags.Parse(os.Args[2:]); err != nil {
 			log.Fatalf("failed to parse mssql flags: %v", err)
 		}
-		bencher = databases.NewMSSQL(*host, *port, *user, *pass, *maxconns)
+		placeholder = databases.AtPlaceholder
+		newBencher = func() benchmark.Bencher { return databases.NewMSSQL(*host, *port, *user, *pass, *maxconns, *prepare) }
 	case "sqlite":
 		sqliteFlags.AddFlagSet(defaultFlags)
+		sqliteFlags.AddFlagSet(prepareFlags)
 		path := sqliteFlags.String("path", "dbbench.sqlite", "database file (sqlite only)")
 		if err := sqliteFlags.Parse(os.Args[2:]); err != nil {
 			log.Fatalf("failed to parse sqlite flags: %v", err)
 		}
-		bencher = databases.NewSQLite(*path)
+		newBencher = func() benchmark.Bencher { return databases.NewSQLite(*path, *prepare) }
+		builtins = databases.SQLiteBenchmarks
 	case "spanner":
 		spannerFlags.AddFlagSet(defaultFlags)
 		spannerFlags.AddFlagSet(gcpFlags)
@@ -134,7 +233,10 @@ func main() {
 		if err := spannerFlags.Parse(os.Args[2:]); err != nil {
 			log.Fatalf("failed to parse spanner flags: %v", err)
 		}
-		bencher = databases.NewSpanner(*projectID, *instanceID, *databaseID, *credentialsFile)
+		placeholder = databases.AtPlaceholder
+		newBencher = func() benchmark.Bencher {
+			return databases.NewSpanner(*projectID, *instanceID, *databaseID, *credentialsFile)
+		}
 	default:
 		if err := defaultFlags.Parse(os.Args[1:]); err != nil {
 			log.Fatalf("failed to parse default flags: %v", err)
@@ -151,110 +253,339 @@ func main() {
 		os.Exit(1)
 	}
 
+	// we need at least one thread
+	if *threads == 0 {
+		*threads = 1
+		fmt.Println("increased to 1 thread")
+	}
+
+	// Parse the script before connecting, a broken script should fail fast.
+	script := benchmark.Script{}
+	if *scriptname != "" {
+		var err error
+		script, err = parseScriptFile(*scriptname, *scriptFormat, *defines)
+		if err != nil {
+			log.Fatalf("failed to parse script: %v\n", err)
+		}
+		for _, w := range script.Warnings {
+			log.Printf("warning: %v", w)
+		}
+	}
+
+	warmupIter, warmupDuration, err := parseWarmup(*warmup)
+	if err != nil {
+		log.Fatalf("failed to parse warmup: %v", err)
+	}
+
+	// a random seed is reported, so the run can be replayed
+	if *seed == 0 {
+		*seed = rand.Uint64()
+		fmt.Fprintf(os.Stderr, "seed: %v (replay with --seed %v)\n", *seed, *seed)
+	}
+	if *runID == "" {
+		*runID = benchmark.NewRunID()
+	}
+
+	opts := benchmark.Options{
+		Iter:           *iter,
+		Threads:        *threads,
+		Seed:           *seed,
+		RunID:          *runID,
+		Placeholder:    placeholder,
+		Duration:       *duration,
+		Rate:           *rate,
+		StmtTimeout:    *stmtTimeout,
+		WarmupIter:     warmupIter,
+		WarmupDuration: warmupDuration,
+		ReportInterval: *interval,
+		OnInterval: func(name string, interval benchmark.Interval) {
+			// print live to stderr, the results might be written to stdout
+			if err := report.WriteInterval(os.Stderr, name, report.NewInterval(interval)); err != nil {
+				log.Printf("failed to write interval: %v", err)
+			}
+		},
+	}
+
+	// only print the statements of the script or the built-in benchmarks and exit
+	if *dryRun {
+		benchmarks := script.Benchmarks
+		if *scriptname == "" {
+			if builtins == nil {
+				log.Fatalf("no built-in benchmarks for %v available yet, use your own script", os.Args[1])
+			}
+			benchmarks = builtins()
+		}
+		if err := printDryRun(os.Stdout, script, selectBenchmarks(benchmarks, *runBench), opts, *dryRunIter); err != nil {
+			log.Fatalf("failed to dry-run: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	// The setup and teardown sections of a script replace the built-in
+	// setup and cleanup, the default tables are neither created nor dropped.
+	ownSections := len(script.Setup) > 0 || len(script.Teardown) > 0
+
 	// only clean old data when clean flag is set
 	if *clean {
-		bencher.Cleanup()
+		if ownSections {
+			teardown(newBencher(), script.Teardown, opts)
+		} else {
+			newBencher().Cleanup()
+		}
 		fmt.Println("cleaned data")
 		os.Exit(0)
 	}
 
-	// setup database
-	if !*nosetup {
-		bencher.Setup()
+	// Load the baseline before connecting, a wrong file should fail fast.
+	var baselineReport report.Report
+	var maxRegression float64
+	if *baseline != "" {
+		var err error
+		baselineReport, err = report.LoadFile(*baseline)
+		if err != nil {
+			log.Fatalf("failed to load baseline: %v", err)
+		}
+		maxRegression, err = report.ParsePercent(*maxRegress)
+		if err != nil {
+			log.Fatalf("failed to parse max. regression: %v", err)
+		}
 	}
 
-	// only cleanup benchmark data when noclean flag is not set
-	if !*noclean {
-		defer bencher.Cleanup()
+	// write the results to stdout or the given file, opened before
+	// connecting, a wrong path or format should fail before the setup
+	out := os.Stdout
+	if *outputFile != "" {
+		f, err := os.Create(*outputFile)
+		if err != nil {
+			log.Fatalf("failed to create output file: %v", err)
+		}
+		defer f.Close()
+		out = f
 	}
 
-	// we need at least one thread
-	if *threads == 0 {
-		*threads = 1
-		fmt.Println("increased to 1 thread")
+	current := report.Report{
+		Config: report.Config{
+			Database: os.Args[1],
+			Iter:     *iter,
+			Threads:  *threads,
+			Conns:    *maxconns,
+			Duration: *duration,
+			Rate:     *rate,
+			Warmup:   *warmup,
+			Seed:     *seed,
+			RunID:    *runID,
+			Script:   *scriptname,
+			Version:  version,
+			Commit:   commit,
+		},
 	}
 
-	// can't have more threads than iterations
-	if *threads > *iter {
-		*threads = *iter
+	writer, err := report.NewWriter(out, *format, current.Config)
+	if err != nil {
+		log.Fatalf("failed to create output: %v", err)
 	}
 
-	benchmarks := bencher.Benchmarks()
+	bencher := newBencher()
 
-	// If a script was specified, overwrite built-in benchmarks.
-	if *scriptname != "" {
-		dat, err := os.ReadFile(*scriptname)
-		if err != nil {
-			log.Fatalf("failed to read file: %v", err)
-		}
-		buf := bytes.NewBuffer(dat)
-		benchmarks, err = benchmark.ParseScript(buf)
-		if err != nil {
-			log.Fatalf("failed to parse script: %v\n", err)
+	// setup database, unless the script sets it up itself
+	if !*nosetup && !ownSections {
+		bencher.Setup()
+	}
+
+	// Exit with the exit code after all other deferred funcs (e.g. b.Cleanup()) ran.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
 		}
+	}()
+
+	// only cleanup benchmark data when noclean flag is not set
+	// and the script doesn't clean up itself
+	if !*noclean && !ownSections {
+		defer bencher.Cleanup()
 	}
 
-	// split benchmark names when "-run 'bench0 bench1 ...'" flag was used
-	toRun := strings.Split(*runBench, " ")
+	// If a script was specified, overwrite built-in benchmarks.
+	benchmarks := script.Benchmarks
+	if *scriptname == "" {
+		benchmarks = bencher.Benchmarks()
+	}
+	selected := selectBenchmarks(benchmarks, *runBench)
+
+	// root context, canceled on SIGINT (ctrl-c)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// restore the default behavior, a second SIGINT terminates immediately
+		stop()
+	}()
+
+	// run the teardown of the script also when the setup failed or the benchmarks got canceled
+	defer teardown(bencher, script.Teardown, opts)
+
+	if err := setup(ctx, bencher, script.Setup, opts); err != nil {
+		log.Printf("failed to setup script: %v", err)
+		exitCode = 1
+		return
+	}
 
 	startTotal := time.Now()
 
-	// notify channel for SIGINT (ctrl-c)
-	sigchan := make(chan os.Signal, 1)
-	signal.Notify(sigchan, os.Interrupt)
+	// consecutive parallel benchmarks are executed together
+	groups := benchmark.Group(selected)
 
-	for i, b := range benchmarks {
+run:
+	for i, group := range groups {
 		select {
-		case <-sigchan:
+		case <-ctx.Done():
 			// got SIGINT, stop benchmarking
-			printTotal(startTotal)
-			// using os.Exit(130) instead of return won't
-			// run deferred funcs (e.g. b.Cleanup())
-			return
+			break run
 		default:
-			// check if we want to run this particular benchmark
-			if !contains(toRun, "all") && !contains(toRun, b.Name) {
-				continue
+			// run the particular benchmark(s)
+			results := benchmark.RunParallel(ctx, bencher, group, opts)
+
+			for j, b := range group {
+				result := report.NewBenchmark(b, results[j])
+				current.Benchmarks = append(current.Benchmarks, result)
+				if err := writer.WriteBenchmark(result); err != nil {
+					log.Printf("failed to write result: %v", err)
+				}
+			}
+
+			// Don't sleep after the last benchmark
+			if i != len(groups)-1 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(pause(group, *sleep)):
+				}
 			}
+		}
+	}
+	flush(writer, startTotal)
 
-			// run the particular benchmark
-			results := benchmark.Run(bencher, b, *iter, *threads)
+	if *baseline == "" {
+		return
+	}
+	// canceled runs are incomplete, they can't pass the comparison
+	if ctx.Err() != nil {
+		log.Println("canceled, the results were not compared with the baseline")
+		exitCode = 1
+		return
+	}
+	// compare with the baseline, the results might be written to stdout, use stderr
+	if compareReports(os.Stderr, baselineReport, current, maxRegression) {
+		exitCode = 1
+	}
+}
 
-			took := results.Duration
-			// execution in ns for mode once
-			nsPerOp := took.Nanoseconds()
+// parseWarmup parses the warmup either as number of iterations or as duration.
+func parseWarmup(s string) (int, time.Duration, error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	if iter, err := strconv.Atoi(s); err == nil {
+		if iter < 0 {
+			return 0, 0, fmt.Errorf("%q is a negative number of iterations", s)
+		}
+		return iter, 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is neither a number of iterations nor a duration", s)
+	}
+	if d < 0 {
+		return 0, 0, fmt.Errorf("%q is a negative duration", s)
+	}
+	return 0, d, nil
+}
 
-			// execution in ns/op for mode loop
-			if b.Type == benchmark.TypeLoop {
-				nsPerOp /= int64(*iter)
-			}
+// compareReports writes the comparison of both reports and
+// returns true when there is a regression.
+func compareReports(w io.Writer, oldReport, newReport report.Report, maxRegression float64) bool {
+	diffs := report.Compare(oldReport, newReport, maxRegression)
+	if err := report.WriteComparison(w, diffs); err != nil {
+		log.Printf("failed to write comparison: %v", err)
+	}
 
-			fmt.Printf(`%v (%vx) took: %v 
-avg: %v, min: %v, max: %v
-%v ops/s
-%v ns/op
-
-`,
-				b.Name,
-				results.TotalExecutionCount,
-				took,
-				results.Avg(),
-				results.Min,
-				results.Max,
-				float64(results.TotalExecutionCount)/results.Duration.Seconds(),
-				nsPerOp)
+	if report.HasRegression(diffs) {
+		fmt.Fprintf(w, "regression of more than %v%% or benchmarks of only one run detected\n", maxRegression)
+		return true
+	}
+	return false
+}
 
-			// Don't sleep after the last benchmark
-			if i != len(benchmarks)-1 {
-				time.Sleep(*sleep)
-			}
+func flush(writer report.Writer, startTotal time.Time) {
+	if err := writer.Flush(time.Since(startTotal)); err != nil {
+		log.Printf("failed to write results: %v", err)
+	}
+}
+
+// selectBenchmarks returns the benchmarks specified with the run flag,
+// e.g. "bench0 bench1" or "all".
+func selectBenchmarks(benchmarks []benchmark.Benchmark, run string) []benchmark.Benchmark {
+	toRun := strings.Split(run, " ")
+
+	selected := []benchmark.Benchmark{}
+	for _, b := range benchmarks {
+		if contains(toRun, "all") || contains(toRun, b.Name) {
+			selected = append(selected, b)
+		}
+	}
+	return selected
+}
+
+// parseScriptFile parses the script with the parser of the format,
+// defines are the 'name=expression' variables of the command line.
+func parseScriptFile(path, format string, defines []string) (benchmark.Script, error) {
+	var vars []benchmark.Var
+	for _, d := range defines {
+		v, err := benchmark.ParseDefine(d)
+		if err != nil {
+			return benchmark.Script{}, fmt.Errorf("failed to parse --define: %v", err)
+		}
+		vars = append(vars, v)
+	}
+
+	switch format {
+	case "dbbench":
+		return benchmark.ParseScriptFile(path, vars...)
+	case "pgbench":
+		return benchmark.ParsePgbenchScriptFile(path, vars...)
+	}
+	return benchmark.Script{}, fmt.Errorf("unknown script format %q, neither 'dbbench' nor 'pgbench'", format)
+}
+
+// printDryRun writes the statements of the script which would be executed,
+// loop benchmarks are limited to the given iterations.
+func printDryRun(w io.Writer, script benchmark.Script, selected []benchmark.Benchmark, opts benchmark.Options, iter int) error {
+	for i, stmt := range script.Setup {
+		if err := printStmt(w, "setup", i, stmt, opts); err != nil {
+			return err
+		}
+	}
+	for _, b := range selected {
+		if err := benchmark.DryRun(w, b, opts, iter); err != nil {
+			return fmt.Errorf("%v: %w", b.Name, err)
+		}
+	}
+	for i, stmt := range script.Teardown {
+		if err := printStmt(w, "teardown", i, stmt, opts); err != nil {
+			return err
 		}
 	}
-	printTotal(startTotal)
+	return nil
 }
 
-func printTotal(startTotal time.Time) {
-	fmt.Printf("total: %v\n", time.Since(startTotal))
+// printStmt writes the ith rendered setup or teardown statement.
+func printStmt(w io.Writer, section string, i int, stmt string, opts benchmark.Options) error {
+	rendered, err := benchmark.Render(stmt, stmtName(section, i), opts)
+	if err != nil {
+		return fmt.Errorf("%v %q: %w", section, stmt, err)
+	}
+	_, err = fmt.Fprintf(w, "-- %v\n%v;\n", section, rendered)
+	return err
 }
 
 func contains(options []string, want string) bool {
@@ -265,3 +596,46 @@ func contains(options []string, want string) bool {
 	}
 	return false
 }
+
+// stmtName returns the name of the ith setup or teardown statement, which
+// derives its random values from the seed.
+func stmtName(section string, i int) string {
+	return fmt.Sprintf("%v %v", section, i+1)
+}
+
+// setup executes the setup statements of the script, it stops at the first failing statement.
+func setup(ctx context.Context, bencher benchmark.Bencher, stmts []string, opts benchmark.Options) error {
+	for i, stmt := range stmts {
+		if err := benchmark.Exec(ctx, bencher, stmt, stmtName("setup", i), opts); err != nil {
+			return fmt.Errorf("%q: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// teardown executes all teardown statements of the script, failing statements are logged.
+func teardown(bencher benchmark.Bencher, stmts []string, opts benchmark.Options) {
+	// don't use the root context, the teardown has to run after ctrl-c
+	ctx := context.Background()
+
+	for i, stmt := range stmts {
+		if err := benchmark.Exec(ctx, bencher, stmt, stmtName("teardown", i), opts); err != nil {
+			log.Printf("failed to teardown script: %q: %v", stmt, err)
+		}
+	}
+}
+
+// pause returns how long to sleep after the group of benchmarks. The longest
+// sleep set by the benchmarks takes precedence over the default.
+func pause(group []benchmark.Benchmark, def time.Duration) time.Duration {
+	longest := time.Duration(0)
+	for _, b := range group {
+		if b.Sleep > longest {
+			longest = b.Sleep
+		}
+	}
+	if longest > 0 {
+		return longest
+	}
+	return def
+}
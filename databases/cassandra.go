@@ -1,6 +1,7 @@
 package databases
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -44,8 +45,14 @@ func NewCassandra(host string, port int, user, password string) *Cassandra {
 }
 
 // Benchmarks returns the individual benchmark functions for the cassandra db.
-// TODO: update is not like other db statements balance = balance + balance!
 func (c *Cassandra) Benchmarks() []benchmark.Benchmark {
+	return CassandraBenchmarks()
+}
+
+// CassandraBenchmarks returns the built-in benchmarks without connecting to
+// the database, e.g. to print them with --dry-run.
+// TODO: update is not like other db statements balance = balance + balance!
+func CassandraBenchmarks() []benchmark.Benchmark {
 	return []benchmark.Benchmark{
 		{Name: "inserts", Type: benchmark.TypeLoop, Stmt: "INSERT INTO dbbench.dbbench_simple (id, balance) VALUES({{.Iter}}, {{call .RandInt64}}) IF NOT EXISTS;"},
 		{Name: "selects", Type: benchmark.TypeLoop, Stmt: "SELECT * FROM dbbench.dbbench_simple WHERE id = {{.Iter}};"},
@@ -80,8 +87,11 @@ func (c *Cassandra) Cleanup() {
 }
 
 // Exec executes the given statement on the database.
-func (c *Cassandra) Exec(stmt string) {
-	if err := c.session.Query(stmt).Exec(); err != nil {
-		log.Fatalf("%v: failed: %v\n", stmt, err)
-	}
+func (c *Cassandra) Exec(ctx context.Context, stmt string) error {
+	return c.ExecArgs(ctx, stmt, nil)
+}
+
+// ExecArgs executes the statement with the arguments of its bind parameters.
+func (c *Cassandra) ExecArgs(ctx context.Context, stmt string, args []any) error {
+	return c.session.Query(stmt, args...).WithContext(ctx).Exec()
 }
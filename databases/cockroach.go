@@ -1,6 +1,7 @@
 package databases
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -10,11 +11,12 @@ import (
 
 // Cockroach implements the bencher interface.
 type Cockroach struct {
-	db *sql.DB
+	db       *sql.DB
+	prepared *preparedStmts // nil unless the statements are prepared
 }
 
 // NewCockroach returns a new cockroach bencher.
-func NewCockroach(host string, port int, user, password string, maxOpenConns int) *Cockroach {
+func NewCockroach(host string, port int, user, password string, maxOpenConns int, prepare bool) *Cockroach {
 	if port == 0 {
 		port = 26257
 	}
@@ -30,11 +32,17 @@ func NewCockroach(host string, port int, user, password string, maxOpenConns int
 	}
 
 	db.SetMaxOpenConns(maxOpenConns)
-	return &Cockroach{db: db}
+	return &Cockroach{db: db, prepared: newPreparedStmts(db, prepare)}
 }
 
 // Benchmarks returns the individual benchmark functions for the cockroach db.
 func (p *Cockroach) Benchmarks() []benchmark.Benchmark {
+	return CockroachBenchmarks()
+}
+
+// CockroachBenchmarks returns the built-in benchmarks without connecting to
+// the database, e.g. to print them with --dry-run.
+func CockroachBenchmarks() []benchmark.Benchmark {
 	return []benchmark.Benchmark{
 		{Name: "inserts", Type: benchmark.TypeLoop, Stmt: "INSERT INTO dbbench.simple (id, balance) VALUES( {{.Iter}}, {{call .RandInt64}});"},
 		{Name: "selects", Type: benchmark.TypeLoop, Stmt: "SELECT * FROM dbbench.simple WHERE id = {{.Iter}};"},
@@ -85,9 +93,16 @@ func (p *Cockroach) Cleanup() {
 }
 
 // Exec executes the given statement on the database.
-func (p *Cockroach) Exec(stmt string) {
-	_, err := p.db.Exec(stmt)
-	if err != nil {
-		log.Printf("%v failed: %v", stmt, err)
-	}
+func (p *Cockroach) Exec(ctx context.Context, stmt string) error {
+	return p.ExecArgs(ctx, stmt, nil)
+}
+
+// ExecArgs executes the statement with the arguments of its bind parameters.
+func (p *Cockroach) ExecArgs(ctx context.Context, stmt string, args []any) error {
+	return execSQL(ctx, p.db, p.prepared, stmt, args)
+}
+
+// Session reserves a connection for executing several statements, e.g. a transaction.
+func (p *Cockroach) Session(ctx context.Context) (benchmark.Session, error) {
+	return newSQLSession(ctx, p.db, p.prepared)
 }
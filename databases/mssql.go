@@ -1,6 +1,7 @@
 package databases
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -11,11 +12,12 @@ import (
 
 // MSSQL implements the bencher interface.
 type MSSQL struct {
-	db *sql.DB
+	db       *sql.DB
+	prepared *preparedStmts // nil unless the statements are prepared
 }
 
 // NewMSSQL returns a new MS SQL bencher.
-func NewMSSQL(host string, port int, user, password string, maxOpenConns int) *MSSQL {
+func NewMSSQL(host string, port int, user, password string, maxOpenConns int, prepare bool) *MSSQL {
 	if port == 0 {
 		port = 1433
 	}
@@ -36,7 +38,7 @@ func NewMSSQL(host string, port int, user, password string, maxOpenConns int) *M
 	}
 
 	db.SetMaxOpenConns(maxOpenConns)
-	p := &MSSQL{db: db}
+	p := &MSSQL{db: db, prepared: newPreparedStmts(db, prepare)}
 	return p
 }
 
@@ -55,9 +57,16 @@ func (m *MSSQL) Cleanup() {
 }
 
 // Exec executes the given statement on the database.
-func (m *MSSQL) Exec(stmt string) {
-	_, err := m.db.Exec(stmt)
-	if err != nil {
-		log.Printf("%v failed: %v", stmt, err)
-	}
+func (m *MSSQL) Exec(ctx context.Context, stmt string) error {
+	return m.ExecArgs(ctx, stmt, nil)
+}
+
+// ExecArgs executes the statement with the arguments of its bind parameters.
+func (m *MSSQL) ExecArgs(ctx context.Context, stmt string, args []any) error {
+	return execSQL(ctx, m.db, m.prepared, stmt, args)
+}
+
+// Session reserves a connection for executing several statements, e.g. a transaction.
+func (m *MSSQL) Session(ctx context.Context) (benchmark.Session, error) {
+	return newSQLSession(ctx, m.db, m.prepared)
 }
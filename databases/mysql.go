@@ -1,6 +1,7 @@
 package databases
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -10,11 +11,12 @@ import (
 
 // Mysql implements the bencher interface.
 type Mysql struct {
-	db *sql.DB
+	db       *sql.DB
+	prepared *preparedStmts // nil unless the statements are prepared
 }
 
 // NewMySQL returns a new mysql bencher.
-func NewMySQL(host string, port int, user, password string, maxOpenConns int) *Mysql {
+func NewMySQL(host string, port int, user, password string, maxOpenConns int, prepare bool) *Mysql {
 	if port == 0 {
 		port = 3306
 	}
@@ -30,12 +32,18 @@ func NewMySQL(host string, port int, user, password string, maxOpenConns int) *M
 	}
 
 	db.SetMaxOpenConns(maxOpenConns)
-	p := &Mysql{db: db}
+	p := &Mysql{db: db, prepared: newPreparedStmts(db, prepare)}
 	return p
 }
 
 // Benchmarks returns the individual benchmark functions for the mysql db.
 func (m *Mysql) Benchmarks() []benchmark.Benchmark {
+	return MySQLBenchmarks()
+}
+
+// MySQLBenchmarks returns the built-in benchmarks without connecting to
+// the database, e.g. to print them with --dry-run.
+func MySQLBenchmarks() []benchmark.Benchmark {
 	return []benchmark.Benchmark{
 		{Name: "inserts", Type: benchmark.TypeLoop, Stmt: "INSERT INTO dbbench.simple (id, balance) VALUES( {{.Iter}}, {{call .RandInt64N 9999999999}});"},
 		{Name: "selects", Type: benchmark.TypeLoop, Stmt: "SELECT * FROM dbbench.simple WHERE id = {{.Iter}};"},
@@ -82,9 +90,16 @@ func (m *Mysql) Cleanup() {
 }
 
 // Exec executes the given statement on the database.
-func (m *Mysql) Exec(stmt string) {
-	_, err := m.db.Exec(stmt)
-	if err != nil {
-		log.Printf("%v failed: %v", stmt, err)
-	}
+func (m *Mysql) Exec(ctx context.Context, stmt string) error {
+	return m.ExecArgs(ctx, stmt, nil)
+}
+
+// ExecArgs executes the statement with the arguments of its bind parameters.
+func (m *Mysql) ExecArgs(ctx context.Context, stmt string, args []any) error {
+	return execSQL(ctx, m.db, m.prepared, stmt, args)
+}
+
+// Session reserves a connection for executing several statements, e.g. a transaction.
+func (m *Mysql) Session(ctx context.Context) (benchmark.Session, error) {
+	return newSQLSession(ctx, m.db, m.prepared)
 }
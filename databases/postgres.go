@@ -1,6 +1,7 @@
 package databases
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -10,11 +11,12 @@ import (
 
 // Postgres implements the bencher interface.
 type Postgres struct {
-	db *sql.DB
+	db       *sql.DB
+	prepared *preparedStmts // nil unless the statements are prepared
 }
 
 // NewPostgres returns a new postgres bencher.
-func NewPostgres(host string, port int, user, password string, maxOpenConns int) *Postgres {
+func NewPostgres(host string, port int, user, password string, maxOpenConns int, prepare bool) *Postgres {
 	if port == 0 {
 		port = 5432
 	}
@@ -31,12 +33,18 @@ func NewPostgres(host string, port int, user, password string, maxOpenConns int)
 
 	db.SetMaxOpenConns(maxOpenConns)
 
-	p := &Postgres{db: db}
+	p := &Postgres{db: db, prepared: newPreparedStmts(db, prepare)}
 	return p
 }
 
 // Benchmarks returns the individual benchmark statements for the postgres db.
 func (p *Postgres) Benchmarks() []benchmark.Benchmark {
+	return PostgresBenchmarks()
+}
+
+// PostgresBenchmarks returns the built-in benchmarks without connecting to
+// the database, e.g. to print them with --dry-run.
+func PostgresBenchmarks() []benchmark.Benchmark {
 	return []benchmark.Benchmark{
 		{Name: "inserts", Type: benchmark.TypeLoop, Stmt: "INSERT INTO dbbench.simple (id, balance) VALUES( {{.Iter}}, {{call .RandInt64}});"},
 		{Name: "selects", Type: benchmark.TypeLoop, Stmt: "SELECT * FROM dbbench.simple WHERE id = {{.Iter}};"},
@@ -87,9 +95,16 @@ func (p *Postgres) Cleanup() {
 }
 
 // Exec executes the given statement on the database.
-func (p *Postgres) Exec(stmt string) {
-	_, err := p.db.Exec(stmt)
-	if err != nil {
-		log.Printf("%v failed: %v", stmt, err)
-	}
+func (p *Postgres) Exec(ctx context.Context, stmt string) error {
+	return p.ExecArgs(ctx, stmt, nil)
+}
+
+// ExecArgs executes the statement with the arguments of its bind parameters.
+func (p *Postgres) ExecArgs(ctx context.Context, stmt string, args []any) error {
+	return execSQL(ctx, p.db, p.prepared, stmt, args)
+}
+
+// Session reserves a connection for executing several statements, e.g. a transaction.
+func (p *Postgres) Session(ctx context.Context) (benchmark.Session, error) {
+	return newSQLSession(ctx, p.db, p.prepared)
 }
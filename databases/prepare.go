@@ -0,0 +1,208 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// maxPreparedStmts limits the cached prepared statements of a pool. Only
+// statements with bind parameters are prepared, the ones exceeding the limit
+// are prepared, executed and closed again.
+const maxPreparedStmts = 1000
+
+// maxPreparedConns limits the connections with statements prepared by sessions.
+// Connections closed by the pool can't be detected, all statements are
+// forgotten when the limit is reached, they are prepared again.
+const maxPreparedConns = 1000
+
+// preparedStmts prepares each statement with bind parameters once on the pool,
+// database/sql prepares it on each connection at its first use there.
+// Statements without bind parameters are executed unprepared, their inlined
+// values usually differ each iteration and they would never be reused.
+type preparedStmts struct {
+	db    *sql.DB
+	mux   sync.Mutex
+	stmts map[string]*sql.Stmt
+	// conns are the statements prepared on the connections of the sessions,
+	// a session reserves its connection only for a single iteration.
+	conns map[driver.Conn]map[string]driver.Stmt
+}
+
+// newPreparedStmts returns the cache of the prepared statements of the pool,
+// or nil when the statements are not prepared.
+func newPreparedStmts(db *sql.DB, prepare bool) *preparedStmts {
+	if !prepare {
+		return nil
+	}
+	// keep the idle connections, their prepared statements are lost when they are closed
+	db.SetMaxIdleConns(math.MaxInt)
+	return &preparedStmts{db: db, stmts: map[string]*sql.Stmt{}, conns: map[driver.Conn]map[string]driver.Stmt{}}
+}
+
+// get returns the prepared statement, uncached is true when it has
+// to be closed after its execution.
+func (p *preparedStmts) get(ctx context.Context, stmt string) (s *sql.Stmt, uncached bool, err error) {
+	p.mux.Lock()
+	s, ok := p.stmts[stmt]
+	p.mux.Unlock()
+	if ok {
+		return s, false, nil
+	}
+
+	// prepare without holding the lock, other statements are not blocked
+	s, err = p.db.PrepareContext(ctx, stmt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if cached, ok := p.stmts[stmt]; ok {
+		// prepared concurrently by another routine
+		_ = s.Close()
+		return cached, false, nil
+	}
+	if len(p.stmts) >= maxPreparedStmts {
+		return s, true, nil
+	}
+	p.stmts[stmt] = s
+	return s, false, nil
+}
+
+// execSQL executes the statement with the arguments of its bind parameters on
+// the pool, as prepared statement unless prepared is nil or there are no arguments.
+func execSQL(ctx context.Context, db *sql.DB, prepared *preparedStmts, stmt string, args []any) error {
+	if prepared == nil || len(args) == 0 {
+		_, err := db.ExecContext(ctx, stmt, args...)
+		return err
+	}
+
+	s, uncached, err := prepared.get(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	if uncached {
+		defer s.Close()
+	}
+	_, err = s.ExecContext(ctx, args...)
+	return err
+}
+
+// execConn executes the statement as prepared statement on the driver
+// connection of a session. The statement is prepared at its first use on
+// the connection and reused by the later sessions of the connection.
+func (p *preparedStmts) execConn(ctx context.Context, conn driver.Conn, stmt string, args []any) error {
+	p.mux.Lock()
+	stmts, ok := p.conns[conn]
+	if !ok {
+		if len(p.conns) >= maxPreparedConns {
+			clear(p.conns)
+		}
+		stmts = map[string]driver.Stmt{}
+		p.conns[conn] = stmts
+	}
+	// the connection is reserved by the session, only the map of all connections is shared
+	s, ok := stmts[stmt]
+	full := len(stmts) >= maxPreparedStmts
+	p.mux.Unlock()
+
+	if !ok {
+		var err error
+		s, err = prepareConn(ctx, conn, stmt)
+		if err != nil {
+			return err
+		}
+		if full {
+			defer s.Close()
+		} else {
+			p.mux.Lock()
+			stmts[stmt] = s
+			p.mux.Unlock()
+		}
+	}
+
+	named, err := namedValues(conn, s, args)
+	if err != nil {
+		return err
+	}
+	err = execDriverStmt(ctx, s, named)
+	if errors.Is(err, driver.ErrBadConn) {
+		// the pool closes the connection
+		p.forget(conn)
+	}
+	return err
+}
+
+// execDriverStmt executes the prepared statement of the driver.
+func execDriverStmt(ctx context.Context, s driver.Stmt, named []driver.NamedValue) error {
+	if execer, ok := s.(driver.StmtExecContext); ok {
+		_, err := execer.ExecContext(ctx, named)
+		return err
+	}
+	values := make([]driver.Value, len(named))
+	for i, nv := range named {
+		values[i] = nv.Value
+	}
+	_, err := s.Exec(values)
+	return err
+}
+
+// forget removes the statements of the connection, e.g. when it's discarded.
+// The statements are closed with the connection.
+func (p *preparedStmts) forget(conn driver.Conn) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	delete(p.conns, conn)
+}
+
+// prepareConn prepares the statement on the driver connection.
+func prepareConn(ctx context.Context, conn driver.Conn, stmt string) (driver.Stmt, error) {
+	if preparer, ok := conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, stmt)
+	}
+	return conn.Prepare(stmt)
+}
+
+// namedValues converts the arguments for the driver, like database/sql does
+// for its own statements.
+func namedValues(conn driver.Conn, s driver.Stmt, args []any) ([]driver.NamedValue, error) {
+	checker, ok := s.(driver.NamedValueChecker)
+	if !ok {
+		checker, _ = conn.(driver.NamedValueChecker)
+	}
+
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nv := driver.NamedValue{Ordinal: i + 1, Value: arg}
+		err := driver.ErrSkip
+		if checker != nil {
+			err = checker.CheckNamedValue(&nv)
+		}
+		if errors.Is(err, driver.ErrSkip) {
+			nv.Value, err = driver.DefaultParameterConverter.ConvertValue(arg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert argument %v: %w", i+1, err)
+		}
+		named[i] = nv
+	}
+	return named, nil
+}
+
+// DollarPlaceholder returns the placeholder of the nth bind parameter
+// of PostgreSQL and CockroachDB, e.g. '$1'.
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// AtPlaceholder returns the placeholder of the nth bind parameter
+// of MS SQL Server and Spanner, e.g. '@p1'.
+func AtPlaceholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
@@ -0,0 +1,71 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/sj14/dbbench/benchmark"
+)
+
+// sqlSession executes the statements on a single connection of the pool.
+type sqlSession struct {
+	conn     *sql.Conn
+	failed   bool           // a statement failed, e.g. a transaction might still be open
+	prepared *preparedStmts // nil when not preparing
+}
+
+// newSQLSession reserves a connection of the pool until the session is closed.
+// The prepared statements of the pool can't be used on a reserved connection,
+// the statements are prepared on the connection itself, once for all of its
+// sessions.
+func newSQLSession(ctx context.Context, db *sql.DB, prepared *preparedStmts) (benchmark.Session, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlSession{conn: conn, prepared: prepared}, nil
+}
+
+// Exec executes the statement on the connection of the session.
+func (s *sqlSession) Exec(ctx context.Context, stmt string) error {
+	return s.ExecArgs(ctx, stmt, nil)
+}
+
+// ExecArgs executes the statement with the arguments of its bind parameters
+// on the connection of the session.
+func (s *sqlSession) ExecArgs(ctx context.Context, stmt string, args []any) error {
+	err := s.exec(ctx, stmt, args)
+	if err != nil {
+		s.failed = true
+	}
+	return err
+}
+
+// exec executes the statement, as prepared statement when preparing
+// a statement with bind parameters.
+func (s *sqlSession) exec(ctx context.Context, stmt string, args []any) error {
+	if s.prepared == nil || len(args) == 0 {
+		_, err := s.conn.ExecContext(ctx, stmt, args...)
+		return err
+	}
+
+	return s.conn.Raw(func(dc any) error {
+		return s.prepared.execConn(ctx, dc.(driver.Conn), stmt, args)
+	})
+}
+
+// Close returns the connection to the pool. The connection is discarded
+// after a failed statement, it's in an unknown state.
+func (s *sqlSession) Close() error {
+	if s.failed {
+		// returning ErrBadConn removes the connection from the pool
+		_ = s.conn.Raw(func(dc any) error {
+			if s.prepared != nil {
+				s.prepared.forget(dc.(driver.Conn))
+			}
+			return driver.ErrBadConn
+		})
+	}
+	return s.conn.Close()
+}
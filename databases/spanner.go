@@ -13,7 +13,6 @@ import (
 // Spanner implements the bencher interface.
 type Spanner struct {
 	client *spanner.Client
-	ctx    context.Context
 }
 
 /*
@@ -47,7 +46,7 @@ func NewSpanner(projectID, instanceID, databaseID, gcpCredentialsFile string) *S
 		log.Fatalf("failed to open connection to spanner: %v", err)
 	}
 
-	return &Spanner{client, ctx}
+	return &Spanner{client}
 }
 
 // Setup initializes the database for the benchmark.
@@ -63,12 +62,21 @@ func (s *Spanner) Benchmarks() (bb []benchmark.Benchmark) {
 }
 
 // Exec executes the given statement on the database.
-func (s *Spanner) Exec(stmt string) {
-	_, err := s.client.ReadWriteTransaction(s.ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
-		txn.Query(ctx, spanner.NewStatement(stmt))
-		return nil
-	})
-	if err != nil {
-		log.Fatalf("%v: failed: %v\n", stmt, err)
+func (s *Spanner) Exec(ctx context.Context, stmt string) error {
+	return s.ExecArgs(ctx, stmt, nil)
+}
+
+// ExecArgs executes the statement with the arguments of its bind parameters,
+// the placeholders are '@p1', '@p2' and so on (AtPlaceholder).
+func (s *Spanner) ExecArgs(ctx context.Context, stmt string, args []any) error {
+	st := spanner.NewStatement(stmt)
+	for i, arg := range args {
+		st.Params[fmt.Sprintf("p%v", i+1)] = arg
 	}
+
+	_, err := s.client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		// consume the rows, otherwise errors of the statement are not reported
+		return txn.Query(ctx, st).Do(func(*spanner.Row) error { return nil })
+	})
+	return err
 }
@@ -1,6 +1,7 @@
 package databases
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -11,7 +12,8 @@ import (
 
 // SQLite implements the bencher interface.
 type SQLite struct {
-	db *sql.DB
+	db       *sql.DB
+	prepared *preparedStmts // nil unless the statements are prepared
 }
 
 var (
@@ -20,7 +22,7 @@ var (
 )
 
 // NewSQLite retruns a new SQLite bencher.
-func NewSQLite(path string) *SQLite {
+func NewSQLite(path string, prepare bool) *SQLite {
 	dbPath = path
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -35,12 +37,18 @@ func NewSQLite(path string) *SQLite {
 	}
 
 	db.SetMaxOpenConns(1)
-	p := &SQLite{db: db}
+	p := &SQLite{db: db, prepared: newPreparedStmts(db, prepare)}
 	return p
 }
 
 // Benchmarks returns the individual benchmark statements for sqlite.
 func (m *SQLite) Benchmarks() []benchmark.Benchmark {
+	return SQLiteBenchmarks()
+}
+
+// SQLiteBenchmarks returns the built-in benchmarks without connecting to
+// the database, e.g. to print them with --dry-run.
+func SQLiteBenchmarks() []benchmark.Benchmark {
 	return []benchmark.Benchmark{
 		{Name: "inserts", Type: benchmark.TypeLoop, Stmt: "INSERT INTO dbbench_simple (id, balance) VALUES( {{.Iter}}, {{call .RandInt64}});"},
 		{Name: "selects", Type: benchmark.TypeLoop, Stmt: "SELECT * FROM dbbench_simple WHERE id = {{.Iter}};"},
@@ -96,10 +104,17 @@ func (m *SQLite) Cleanup() {
 }
 
 // Exec executes the given statement on the database.
-func (m *SQLite) Exec(stmt string) {
+func (m *SQLite) Exec(ctx context.Context, stmt string) error {
+	return m.ExecArgs(ctx, stmt, nil)
+}
+
+// ExecArgs executes the statement with the arguments of its bind parameters.
+func (m *SQLite) ExecArgs(ctx context.Context, stmt string, args []any) error {
 	//  driver has no support for results
-	_, err := m.db.Exec(stmt)
-	if err != nil {
-		log.Printf("%v failed: %v", stmt, err)
-	}
+	return execSQL(ctx, m.db, m.prepared, stmt, args)
+}
+
+// Session reserves a connection for executing several statements, e.g. a transaction.
+func (m *SQLite) Session(ctx context.Context) (benchmark.Session, error) {
+	return newSQLSession(ctx, m.db, m.prepared)
 }
@@ -4,6 +4,7 @@ go 1.25.8
 
 require (
 	cloud.google.com/go/spanner v1.92.0
+	github.com/HdrHistogram/hdrhistogram-go v1.3.0
 	github.com/denisenkom/go-mssqldb v0.12.3
 	github.com/go-sql-driver/mysql v1.10.0
 	github.com/gocql/gocql v1.7.0
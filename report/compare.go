@@ -0,0 +1,201 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Diff is the change of a metric between two runs of the same benchmark.
+type Diff struct {
+	Benchmark string
+	Metric    string
+	Old       float64
+	New       float64
+	// Change is the relative change in percent, positive values are regressions
+	// (less throughput or higher latency).
+	Change     float64
+	Regression bool
+	// Only is "old" or "new" when the benchmark is only part of that report,
+	// e.g. it was renamed or failed to run. It has no metrics.
+	Only string
+	// Ambiguous is set when several benchmarks of a report have the name,
+	// their metrics can't be told apart. It has no metrics.
+	Ambiguous bool
+}
+
+// metric describes how a value of a benchmark is compared.
+type metric struct {
+	name           string
+	value          func(Benchmark) float64
+	higherIsBetter bool
+}
+
+func latency(f func(Benchmark) time.Duration) func(Benchmark) float64 {
+	return func(b Benchmark) float64 { return float64(f(b)) }
+}
+
+var metrics = []metric{
+	{name: "ops/s", value: func(b Benchmark) float64 { return b.OpsPerSec }, higherIsBetter: true},
+	{name: "p50", value: latency(func(b Benchmark) time.Duration { return b.P50 })},
+	{name: "p90", value: latency(func(b Benchmark) time.Duration { return b.P90 })},
+	{name: "p95", value: latency(func(b Benchmark) time.Duration { return b.P95 })},
+	{name: "p99", value: latency(func(b Benchmark) time.Duration { return b.P99 })},
+	{name: "p99.9", value: latency(func(b Benchmark) time.Duration { return b.P999 })},
+}
+
+// Load reads a report in the JSON format.
+func Load(r io.Reader) (Report, error) {
+	report := Report{}
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return Report{}, err
+	}
+	return report, nil
+}
+
+// LoadFile reads a report in the JSON format from the given file.
+func LoadFile(path string) (Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Report{}, err
+	}
+	defer f.Close()
+
+	report, err := Load(f)
+	if err != nil {
+		return Report{}, fmt.Errorf("%v: %w", path, err)
+	}
+	return report, nil
+}
+
+// Compare diffs the benchmarks and the statements of mixed benchmarks of both
+// reports by their name. Benchmarks which are only part of one report are
+// returned with Only set, names of several benchmarks of a report with
+// Ambiguous set. A change worse than maxRegression (in percent) is marked
+// as regression.
+func Compare(old, new Report, maxRegression float64) []Diff {
+	oldByName := make(map[string]Benchmark, len(old.Benchmarks))
+	ambiguous := map[string]bool{}
+	for _, b := range flatten(old.Benchmarks) {
+		if _, ok := oldByName[b.Name]; ok {
+			ambiguous[b.Name] = true
+		}
+		oldByName[b.Name] = b
+	}
+	newByName := make(map[string]Benchmark, len(new.Benchmarks))
+	for _, b := range flatten(new.Benchmarks) {
+		if _, ok := newByName[b.Name]; ok {
+			ambiguous[b.Name] = true
+		}
+		newByName[b.Name] = b
+	}
+
+	diffs := []Diff{}
+	done := map[string]bool{} // each name is reported once
+	for _, newBench := range flatten(new.Benchmarks) {
+		if done[newBench.Name] {
+			continue
+		}
+		done[newBench.Name] = true
+
+		oldBench, ok := oldByName[newBench.Name]
+		if !ok {
+			diffs = append(diffs, Diff{Benchmark: newBench.Name, Only: "new"})
+			continue
+		}
+		if ambiguous[newBench.Name] {
+			diffs = append(diffs, Diff{Benchmark: newBench.Name, Ambiguous: true})
+			continue
+		}
+
+		for _, m := range metrics {
+			d := Diff{
+				Benchmark: newBench.Name,
+				Metric:    m.name,
+				Old:       m.value(oldBench),
+				New:       m.value(newBench),
+			}
+
+			if d.Old != 0 {
+				d.Change = (d.New - d.Old) / d.Old * 100
+				if m.higherIsBetter {
+					d.Change = -d.Change
+				}
+			}
+			d.Regression = d.Change > maxRegression
+
+			diffs = append(diffs, d)
+		}
+	}
+
+	for _, oldBench := range flatten(old.Benchmarks) {
+		if _, ok := newByName[oldBench.Name]; !ok && !done[oldBench.Name] {
+			done[oldBench.Name] = true
+			diffs = append(diffs, Diff{Benchmark: oldBench.Name, Only: "old"})
+		}
+	}
+	return diffs
+}
+
+// HasRegression returns true when at least one of the diffs is a regression,
+// a benchmark which is only part of one report or an ambiguous name.
+func HasRegression(diffs []Diff) bool {
+	for _, d := range diffs {
+		if d.Regression || d.Only != "" || d.Ambiguous {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteComparison writes the diffs as a table.
+func WriteComparison(w io.Writer, diffs []Diff) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "benchmark\tmetric\told\tnew\tchange")
+
+	for _, d := range diffs {
+		if d.Only != "" {
+			fmt.Fprintf(tw, "%v\t-\t-\t-\t-\tONLY IN %v\n", d.Benchmark, strings.ToUpper(d.Only))
+			continue
+		}
+		if d.Ambiguous {
+			fmt.Fprintf(tw, "%v\t-\t-\t-\t-\tAMBIGUOUS\n", d.Benchmark)
+			continue
+		}
+
+		format := func(v float64) string {
+			if d.Metric == "ops/s" {
+				return strconv.FormatFloat(v, 'f', 2, 64)
+			}
+			return time.Duration(v).String()
+		}
+
+		status := ""
+		if d.Regression {
+			status = "\tREGRESSION"
+		}
+
+		// show the change in the direction of the value, e.g. +10% ops/s
+		change := d.Change
+		if d.Metric == "ops/s" {
+			change = -change
+		}
+
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%+.2f%%%v\n", d.Benchmark, d.Metric, format(d.Old), format(d.New), change, status)
+	}
+	return tw.Flush()
+}
+
+// ParsePercent parses a percentage like "10%" or "10".
+func ParsePercent(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,140 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	old := Report{Benchmarks: []Benchmark{
+		{Name: "inserts", OpsPerSec: 1000, P50: time.Millisecond, P99: 10 * time.Millisecond},
+		{Name: "removed", OpsPerSec: 1000},
+	}}
+	new := Report{Benchmarks: []Benchmark{
+		{Name: "inserts", OpsPerSec: 850, P50: time.Millisecond, P99: 10500 * time.Microsecond},
+		{Name: "added", OpsPerSec: 1000},
+	}}
+
+	diffs := Compare(old, new, 10)
+
+	byMetric := map[string]Diff{}
+	only := map[string]string{}
+	for _, d := range diffs {
+		if d.Only != "" {
+			only[d.Benchmark] = d.Only
+			continue
+		}
+		require.Equal(t, "inserts", d.Benchmark)
+		byMetric[d.Metric] = d
+	}
+	assert.Equal(t, map[string]string{"added": "new", "removed": "old"}, only)
+
+	assert.InDelta(t, 15, byMetric["ops/s"].Change, 0.001)
+	assert.True(t, byMetric["ops/s"].Regression)
+	assert.InDelta(t, 0, byMetric["p50"].Change, 0.001)
+	assert.False(t, byMetric["p50"].Regression)
+	assert.InDelta(t, 5, byMetric["p99"].Change, 0.001)
+	assert.False(t, byMetric["p99"].Regression)
+	assert.True(t, HasRegression(diffs))
+
+	// the benchmarks of only one report fail the comparison
+	assert.True(t, HasRegression(Compare(old, new, 20)))
+	old.Benchmarks, new.Benchmarks = old.Benchmarks[:1], new.Benchmarks[:1]
+	assert.False(t, HasRegression(Compare(old, new, 20)))
+}
+
+func TestCompareAmbiguous(t *testing.T) {
+	old := Report{Benchmarks: []Benchmark{
+		{Name: "inserts", OpsPerSec: 1000},
+		{Name: "inserts", OpsPerSec: 10},
+		{Name: "removed", OpsPerSec: 1000},
+		{Name: "removed", OpsPerSec: 1000},
+	}}
+	new := Report{Benchmarks: []Benchmark{
+		{Name: "inserts", OpsPerSec: 1000},
+		{Name: "selects", OpsPerSec: 1000},
+		{Name: "selects", OpsPerSec: 1000},
+	}}
+
+	// the name is reported once instead of comparing one of the benchmarks
+	diffs := Compare(old, new, 10)
+	assert.Equal(t, []Diff{
+		{Benchmark: "inserts", Ambiguous: true},
+		{Benchmark: "selects", Only: "new"},
+		{Benchmark: "removed", Only: "old"},
+	}, diffs)
+	assert.True(t, HasRegression(diffs))
+
+	// duplicates of the new report are ambiguous as well
+	diffs = Compare(Report{Benchmarks: new.Benchmarks[1:2]}, new, 10)
+	assert.Equal(t, []Diff{{Benchmark: "inserts", Only: "new"}, {Benchmark: "selects", Ambiguous: true}}, diffs)
+}
+
+func TestLoad(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, "json", testConfig)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBenchmark(testBenchmark))
+	require.NoError(t, w.Flush(time.Second))
+
+	got, err := Load(buf)
+	require.NoError(t, err)
+	assert.Equal(t, []Benchmark{testBenchmark}, got.Benchmarks)
+
+	_, err = Load(strings.NewReader("inserts 1000 ops/s"))
+	require.Error(t, err)
+}
+
+func TestWriteComparison(t *testing.T) {
+	diffs := []Diff{
+		{Benchmark: "inserts", Metric: "ops/s", Old: 1000, New: 850, Change: 15, Regression: true},
+		{Benchmark: "inserts", Metric: "p99", Old: float64(10 * time.Millisecond), New: float64(9 * time.Millisecond), Change: -10},
+		{Benchmark: "deletes", Only: "old"},
+		{Benchmark: "updates", Ambiguous: true},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteComparison(buf, diffs))
+
+	assert.Equal(t, `benchmark  metric  old      new     change
+inserts    ops/s   1000.00  850.00  -15.00%  REGRESSION
+inserts    p99     10ms     9ms     -10.00%
+deletes    -       -        -       -  ONLY IN OLD
+updates    -       -        -       -  AMBIGUOUS
+`, buf.String())
+}
+
+func TestParsePercent(t *testing.T) {
+	for in, want := range map[string]float64{"10%": 10, "2.5": 2.5, " 0% ": 0} {
+		got, err := ParsePercent(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	_, err := ParsePercent("ten")
+	require.Error(t, err)
+}
+
+func TestCompareStatements(t *testing.T) {
+	old := Report{Benchmarks: []Benchmark{
+		{Name: "oltp", OpsPerSec: 1000, Statements: []Benchmark{{Name: "select", OpsPerSec: 800}}},
+	}}
+	new := Report{Benchmarks: []Benchmark{
+		{Name: "oltp", OpsPerSec: 1000, Statements: []Benchmark{{Name: "select", OpsPerSec: 400}}},
+	}}
+
+	diffs := Compare(old, new, 10)
+
+	regressions := []string{}
+	for _, d := range diffs {
+		if d.Regression {
+			regressions = append(regressions, d.Benchmark+" "+d.Metric)
+		}
+	}
+	assert.Equal(t, []string{"oltp/select ops/s"}, regressions)
+}
@@ -0,0 +1,175 @@
+// Package report writes the benchmark results in different formats.
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sj14/dbbench/benchmark"
+)
+
+// Report contains the configuration and the results of a run.
+type Report struct {
+	Config     Config        `json:"config"`
+	Benchmarks []Benchmark   `json:"benchmarks"`
+	Total      time.Duration `json:"total_ns"`
+}
+
+// Config describes the run which produced the results.
+type Config struct {
+	Database string        `json:"database"`
+	Iter     int           `json:"iter"`
+	Threads  int           `json:"threads"`
+	Conns    int           `json:"conns"`
+	Duration time.Duration `json:"duration_ns,omitempty"`
+	Rate     float64       `json:"rate,omitempty"`
+	Warmup   string        `json:"warmup,omitempty"`
+	Seed     uint64        `json:"seed,omitempty"`
+	RunID    string        `json:"run_id,omitempty"`
+	Script   string        `json:"script,omitempty"`
+	Version  string        `json:"version"`
+	Commit   string        `json:"commit"`
+}
+
+// Benchmark contains the result of a single benchmark.
+type Benchmark struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Threads   int               `json:"threads,omitempty"`
+	Count     uint64            `json:"count"`
+	Successes uint64            `json:"successes"`
+	Errors    uint64            `json:"errors"`
+	ErrorsBy  map[string]uint64 `json:"errors_by_class,omitempty"`
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	Duration  time.Duration     `json:"duration_ns"`
+	OpsPerSec float64           `json:"ops_per_sec"`
+	NsPerOp   int64             `json:"ns_per_op"`
+	Avg       time.Duration     `json:"avg_ns"`
+	Min       time.Duration     `json:"min_ns"`
+	Max       time.Duration     `json:"max_ns"`
+	StdDev    time.Duration     `json:"stddev_ns"`
+	P50       time.Duration     `json:"p50_ns"`
+	P90       time.Duration     `json:"p90_ns"`
+	P95       time.Duration     `json:"p95_ns"`
+	P99       time.Duration     `json:"p99_ns"`
+	P999      time.Duration     `json:"p999_ns"`
+	Intervals []Interval        `json:"intervals,omitempty"`
+	// Weight is only set for the statements of mixed benchmarks. Statements are
+	// the mixed statements, or the statements of each iteration with \breakdown.
+	Weight     int         `json:"weight,omitempty"`
+	Statements []Benchmark `json:"statements,omitempty"`
+}
+
+// Interval contains the metrics of a single reporting interval.
+type Interval struct {
+	Start     time.Time     `json:"start"`
+	Duration  time.Duration `json:"duration_ns"`
+	Successes uint64        `json:"successes"`
+	Errors    uint64        `json:"errors"`
+	OpsPerSec float64       `json:"ops_per_sec"`
+	P50       time.Duration `json:"p50_ns"`
+	P90       time.Duration `json:"p90_ns"`
+	P95       time.Duration `json:"p95_ns"`
+	P99       time.Duration `json:"p99_ns"`
+	P999      time.Duration `json:"p999_ns"`
+	Max       time.Duration `json:"max_ns"`
+}
+
+// NewInterval converts the metrics of a reporting interval.
+func NewInterval(i benchmark.Interval) Interval {
+	return Interval{
+		Start:     i.Start,
+		Duration:  i.Duration,
+		Successes: i.SuccessCount,
+		Errors:    i.ErrorCount,
+		OpsPerSec: i.OpsPerSec(),
+		P50:       i.P50,
+		P90:       i.P90,
+		P95:       i.P95,
+		P99:       i.P99,
+		P999:      i.P999,
+		Max:       i.Max,
+	}
+}
+
+// NewBenchmark converts the result of the given benchmark.
+func NewBenchmark(b benchmark.Benchmark, r benchmark.Result) Benchmark {
+	// execution in ns for mode once
+	nsPerOp := r.Duration.Nanoseconds()
+
+	// execution in ns/op for mode loop
+	if b.Type == benchmark.TypeLoop && r.TotalExecutionCount > 0 {
+		nsPerOp /= int64(r.TotalExecutionCount)
+	}
+
+	var opsPerSec float64
+	if r.Duration > 0 {
+		opsPerSec = float64(r.SuccessCount) / r.Duration.Seconds()
+	}
+
+	var intervals []Interval
+	for _, i := range r.Intervals {
+		intervals = append(intervals, NewInterval(i))
+	}
+
+	var statements []Benchmark
+	for i, m := range b.Mix {
+		s := NewBenchmark(benchmark.Benchmark{Name: m.Name, Type: b.Type, Stmt: m.Stmt, Delimiter: b.Delimiter}, r.Mix[i])
+		s.Weight = m.Weight
+		statements = append(statements, s)
+	}
+	if len(b.Mix) == 0 {
+		for i, s := range r.Statements {
+			statements = append(statements, NewBenchmark(benchmark.Benchmark{Name: statementName(i), Type: b.Type}, s))
+		}
+	}
+
+	return Benchmark{
+		Name:       b.Name,
+		Type:       b.Type.String(),
+		Threads:    r.Threads,
+		Count:      r.TotalExecutionCount,
+		Successes:  r.SuccessCount,
+		Errors:     r.ErrorCount,
+		ErrorsBy:   r.Errors,
+		Start:      r.Start,
+		End:        r.End,
+		Duration:   r.Duration,
+		OpsPerSec:  opsPerSec,
+		NsPerOp:    nsPerOp,
+		Avg:        r.Avg(),
+		Min:        r.Min,
+		Max:        r.Max,
+		StdDev:     r.StdDev(),
+		P50:        r.Percentile(50),
+		P90:        r.Percentile(90),
+		P95:        r.Percentile(95),
+		P99:        r.Percentile(99),
+		P999:       r.Percentile(99.9),
+		Intervals:  intervals,
+		Statements: statements,
+	}
+}
+
+// statementName returns the name of the i-th statement of an iteration. Like
+// unnamed mixed statements, it's named by its position, the text of the
+// statement is long and changes with its template, e.g. between compared runs.
+func statementName(i int) string {
+	return fmt.Sprintf("stmt %v", i+1)
+}
+
+// flatten returns each benchmark followed by its statements, the statements
+// are named "benchmark/statement" and executed by the threads of the benchmark.
+func flatten(benchmarks []Benchmark) []Benchmark {
+	flat := []Benchmark{}
+	for _, b := range benchmarks {
+		flat = append(flat, b)
+		for _, s := range flatten(b.Statements) {
+			s.Name = b.Name + "/" + s.Name
+			s.Threads = b.Threads
+			flat = append(flat, s)
+		}
+	}
+	return flat
+}
@@ -0,0 +1,276 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sj14/dbbench/benchmark"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testConfig = Config{Database: "sqlite", Iter: 100, Threads: 4, Warmup: "10", Seed: 42, RunID: "run1", Version: "v1", Commit: "abc"}
+
+	testBenchmark = Benchmark{
+		Name:      "inserts",
+		Type:      "loop",
+		Count:     100,
+		Successes: 98,
+		Errors:    2,
+		ErrorsBy:  map[string]uint64{"UNIQUE constraint failed": 2},
+		Duration:  time.Second,
+		OpsPerSec: 98,
+		NsPerOp:   10000000,
+		Avg:       10 * time.Millisecond,
+		Min:       time.Millisecond,
+		Max:       50 * time.Millisecond,
+		P50:       9 * time.Millisecond,
+		P99:       40 * time.Millisecond,
+	}
+)
+
+func TestNewBenchmark(t *testing.T) {
+	r := benchmark.Result{
+		Duration:            2 * time.Second,
+		TotalExecutionCount: 10,
+		SuccessCount:        8,
+		ErrorCount:          2,
+		TotalExecutionTime:  80 * time.Millisecond,
+		Threads:             3,
+	}
+
+	got := NewBenchmark(benchmark.Benchmark{Name: "test", Type: benchmark.TypeLoop}, r)
+
+	assert.Equal(t, "test", got.Name)
+	assert.Equal(t, "loop", got.Type)
+	assert.Equal(t, 3, got.Threads)
+	assert.Equal(t, uint64(10), got.Count)
+	assert.Equal(t, uint64(2), got.Errors)
+	assert.Equal(t, 4.0, got.OpsPerSec)
+	assert.Equal(t, int64(200*time.Millisecond), got.NsPerOp)
+	assert.Equal(t, 10*time.Millisecond, got.Avg)
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, "xml", testConfig)
+	require.Error(t, err)
+}
+
+func TestText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, "text", testConfig)
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteBenchmark(testBenchmark))
+	require.NoError(t, w.Flush(3*time.Second))
+
+	assert.Equal(t, `inserts (100x) took: 1s
+avg: 10ms, min: 1ms, max: 50ms, stddev: 0s
+p50: 9ms, p90: 0s, p95: 0s, p99: 40ms, p99.9: 0s
+98 ops/s
+10000000 ns/op
+2 errors:
+  2x UNIQUE constraint failed
+
+total: 3s
+`, buf.String())
+}
+
+func TestJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, "json", testConfig)
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteBenchmark(testBenchmark))
+	require.NoError(t, w.Flush(3*time.Second))
+
+	got := Report{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+
+	assert.Equal(t, Report{
+		Config:     testConfig,
+		Benchmarks: []Benchmark{testBenchmark},
+		Total:      3 * time.Second,
+	}, got)
+}
+
+func TestCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, "csv", testConfig)
+	require.NoError(t, err)
+
+	// the second benchmark overrides the threads
+	overridden := testBenchmark
+	overridden.Threads = 1
+	require.NoError(t, w.WriteBenchmark(testBenchmark))
+	require.NoError(t, w.WriteBenchmark(overridden))
+	require.NoError(t, w.Flush(3*time.Second))
+
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	threads := slices.Index(csvHeader, "threads")
+	assert.Equal(t, "4", records[1][threads])
+	assert.Equal(t, "1", records[2][threads])
+	assert.Equal(t, csvHeader, records[0])
+
+	row := map[string]string{}
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	assert.Equal(t, "sqlite", row["database"])
+	assert.Equal(t, "10", row["warmup"])
+	assert.Equal(t, "42", row["seed"])
+	assert.Equal(t, "run1", row["run_id"])
+	assert.Equal(t, "inserts", row["name"])
+	assert.Equal(t, "98.00", row["ops_per_sec"])
+	assert.Equal(t, "40000000", row["p99_ns"])
+}
+
+func TestMarkdown(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, "markdown", testConfig)
+	require.NoError(t, err)
+
+	b := testBenchmark
+	b.Name = "a|b"
+	b.Threads = 8
+	require.NoError(t, w.WriteBenchmark(b))
+	require.NoError(t, w.Flush(3*time.Second))
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, "| sqlite | 100 | 4 | 0 | 0s | 0 | 10 | 42 | run1 | v1 | abc |", lines[2])
+	assert.Equal(t, `| a\|b | 8 | 100 | 2 | 1s | 98.00 | 10ms | 1ms | 9ms | 0s | 0s | 40ms | 0s | 50ms |`, lines[6])
+	assert.Equal(t, "total: 3s", lines[8])
+}
+
+func TestWriteInterval(t *testing.T) {
+	buf := &bytes.Buffer{}
+	i := Interval{
+		Start:     time.Date(2024, 1, 1, 13, 37, 0, 0, time.UTC),
+		OpsPerSec: 1234.5,
+		Errors:    3,
+		P50:       time.Millisecond,
+		P99:       20 * time.Millisecond,
+		Max:       time.Second,
+	}
+
+	require.NoError(t, WriteInterval(buf, "inserts", i))
+
+	assert.Equal(t, "inserts [13:37:00] 1234.50 ops/s, 3 errors, p50: 1ms, p99: 20ms, max: 1s\n", buf.String())
+}
+
+func TestMixStatements(t *testing.T) {
+	b := benchmark.Benchmark{Name: "oltp", Type: benchmark.TypeLoop, Mix: []benchmark.MixStmt{
+		{Name: "select", Weight: 80, Stmt: "SELECT 1"},
+		{Name: "update", Weight: 20, Stmt: "UPDATE 1"},
+	}}
+	r := benchmark.Result{
+		Duration:            time.Second,
+		TotalExecutionCount: 10,
+		SuccessCount:        10,
+		Mix: []benchmark.Result{
+			{Duration: time.Second, TotalExecutionCount: 8, SuccessCount: 8},
+			{Duration: time.Second, TotalExecutionCount: 2, SuccessCount: 2},
+		},
+	}
+
+	got := NewBenchmark(b, r)
+	require.Len(t, got.Statements, 2)
+	assert.Equal(t, "select", got.Statements[0].Name)
+	assert.Equal(t, 80, got.Statements[0].Weight)
+	assert.Equal(t, 8.0, got.Statements[0].OpsPerSec)
+
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, "text", testConfig)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBenchmark(got))
+	assert.Contains(t, buf.String(), `statements:
+  select (weight 80): 8x, 8.00 ops/s, avg: 0s, p50: 0s, p99: 0s, 0 errors
+  update (weight 20): 2x, 2.00 ops/s, avg: 0s, p50: 0s, p99: 0s, 0 errors
+`)
+
+	buf.Reset()
+	w, err = NewWriter(buf, "csv", testConfig)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBenchmark(got))
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, "oltp/update", records[3][slices.Index(csvHeader, "name")])
+}
+
+func TestBreakdownStatements(t *testing.T) {
+	b := benchmark.Benchmark{Name: "tx", Type: benchmark.TypeLoop, Breakdown: true,
+		Stmt: "BEGIN;\nINSERT INTO accounts (id, balance)\n  VALUES ({{.Iter}}, 100);\nCOMMIT;"}
+	r := benchmark.Result{
+		Duration:            time.Second,
+		TotalExecutionCount: 10,
+		SuccessCount:        9,
+		ErrorCount:          1,
+		Statements: []benchmark.Result{
+			{Duration: time.Second, TotalExecutionCount: 10, SuccessCount: 10},
+			{Duration: time.Second, TotalExecutionCount: 10, SuccessCount: 9, ErrorCount: 1},
+			{Duration: time.Second, TotalExecutionCount: 9, SuccessCount: 9},
+			{Duration: time.Second, TotalExecutionCount: 1, SuccessCount: 1},
+		},
+	}
+
+	got := NewBenchmark(b, r)
+	require.Len(t, got.Statements, 4)
+	assert.Equal(t, "stmt 1", got.Statements[0].Name)
+	assert.Equal(t, "stmt 2", got.Statements[1].Name)
+	assert.Equal(t, uint64(1), got.Statements[1].Errors)
+	assert.Equal(t, "stmt 3", got.Statements[2].Name)
+	// more statements than in the script, e.g. rendered by a template loop
+	assert.Equal(t, "stmt 4", got.Statements[3].Name)
+
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, "text", testConfig)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBenchmark(got))
+	assert.Contains(t, buf.String(), `statements:
+  stmt 1: 10x, 10.00 ops/s, avg: 0s, p50: 0s, p99: 0s, 0 errors
+  stmt 2: 10x, 9.00 ops/s, avg: 0s, p50: 0s, p99: 0s, 1 errors
+`)
+}
+
+func TestMixBreakdownStatements(t *testing.T) {
+	b := benchmark.Benchmark{Name: "oltp", Type: benchmark.TypeLoop, Breakdown: true, Mix: []benchmark.MixStmt{
+		{Name: "transfer", Weight: 1, Stmt: "UPDATE a // UPDATE b //"},
+	}, Delimiter: "//"}
+	r := benchmark.Result{
+		Duration: time.Second,
+		Mix: []benchmark.Result{{
+			Duration: time.Second,
+			Statements: []benchmark.Result{
+				{Duration: time.Second, TotalExecutionCount: 1, SuccessCount: 1},
+				{Duration: time.Second, TotalExecutionCount: 1, SuccessCount: 1},
+			},
+		}},
+	}
+
+	got := NewBenchmark(b, r)
+	require.Len(t, got.Statements, 1)
+	require.Len(t, got.Statements[0].Statements, 2)
+	assert.Equal(t, "stmt 2", got.Statements[0].Statements[1].Name)
+
+	flat := flatten([]Benchmark{got})
+	require.Len(t, flat, 4)
+	assert.Equal(t, "oltp/transfer/stmt 2", flat[3].Name)
+
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, "text", testConfig)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBenchmark(got))
+	assert.Contains(t, buf.String(), `statements:
+  transfer (weight 1): 0x, 0.00 ops/s, avg: 0s, p50: 0s, p99: 0s, 0 errors
+    stmt 1: 1x, 1.00 ops/s, avg: 0s, p50: 0s, p99: 0s, 0 errors
+`)
+}
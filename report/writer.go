@@ -0,0 +1,272 @@
+package report
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{"text", "json", "csv", "markdown"}
+
+// Writer writes the benchmark results in a specific format.
+type Writer interface {
+	// WriteBenchmark is called after each finished benchmark.
+	WriteBenchmark(Benchmark) error
+	// Flush is called once after all benchmarks finished.
+	Flush(total time.Duration) error
+}
+
+// NewWriter returns a writer for the given format.
+func NewWriter(w io.Writer, format string, cfg Config) (Writer, error) {
+	switch format {
+	case "text":
+		return &textWriter{w: w}, nil
+	case "json":
+		return &jsonWriter{w: w, report: Report{Config: cfg, Benchmarks: []Benchmark{}}}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w), cfg: cfg}, nil
+	case "markdown":
+		return &markdownWriter{w: w, report: Report{Config: cfg}}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, supported formats: %v", format, Formats)
+}
+
+// textWriter writes a human readable block for each benchmark as soon as it finished.
+type textWriter struct {
+	w io.Writer
+}
+
+func (t *textWriter) WriteBenchmark(b Benchmark) error {
+	_, err := fmt.Fprintf(t.w, `%v (%vx) took: %v
+avg: %v, min: %v, max: %v, stddev: %v
+p50: %v, p90: %v, p95: %v, p99: %v, p99.9: %v
+%v ops/s
+%v ns/op
+`,
+		b.Name,
+		b.Count,
+		b.Duration,
+		b.Avg,
+		b.Min,
+		b.Max,
+		b.StdDev,
+		b.P50,
+		b.P90,
+		b.P95,
+		b.P99,
+		b.P999,
+		b.OpsPerSec,
+		b.NsPerOp)
+	if err != nil {
+		return err
+	}
+
+	if b.Errors > 0 {
+		if _, err := fmt.Fprintf(t.w, "%v errors:\n", b.Errors); err != nil {
+			return err
+		}
+		for _, class := range errorClasses(b.ErrorsBy) {
+			if _, err := fmt.Fprintf(t.w, "  %vx %v\n", b.ErrorsBy[class], class); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(b.Statements) > 0 {
+		if _, err := fmt.Fprintln(t.w, "statements:"); err != nil {
+			return err
+		}
+		if err := t.writeStatements(b.Statements, "  "); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(t.w)
+	return err
+}
+
+// writeStatements writes a line for each statement, followed by its own statements.
+func (t *textWriter) writeStatements(statements []Benchmark, indent string) error {
+	for _, s := range statements {
+		name := s.Name
+		if s.Weight > 0 {
+			name = fmt.Sprintf("%v (weight %v)", s.Name, s.Weight)
+		}
+		if _, err := fmt.Fprintf(t.w, "%v%v: %vx, %.2f ops/s, avg: %v, p50: %v, p99: %v, %v errors\n",
+			indent, name, s.Count, s.OpsPerSec, s.Avg, s.P50, s.P99, s.Errors); err != nil {
+			return err
+		}
+		if err := t.writeStatements(s.Statements, indent+"  "); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *textWriter) Flush(total time.Duration) error {
+	_, err := fmt.Fprintf(t.w, "total: %v\n", total)
+	return err
+}
+
+// WriteInterval writes a single line with the metrics of the interval.
+func WriteInterval(w io.Writer, name string, i Interval) error {
+	_, err := fmt.Fprintf(w, "%v [%v] %.2f ops/s, %v errors, p50: %v, p99: %v, max: %v\n",
+		name, i.Start.Format("15:04:05"), i.OpsPerSec, i.Errors, i.P50, i.P99, i.Max)
+	return err
+}
+
+// errorClasses returns the error classes, the most frequent first.
+func errorClasses(errs map[string]uint64) []string {
+	classes := make([]string, 0, len(errs))
+	for class := range errs {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if errs[classes[i]] != errs[classes[j]] {
+			return errs[classes[i]] > errs[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+	return classes
+}
+
+// jsonWriter writes a single JSON document after all benchmarks finished.
+type jsonWriter struct {
+	w      io.Writer
+	report Report
+}
+
+func (j *jsonWriter) WriteBenchmark(b Benchmark) error {
+	j.report.Benchmarks = append(j.report.Benchmarks, b)
+	return nil
+}
+
+func (j *jsonWriter) Flush(total time.Duration) error {
+	j.report.Total = total
+
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(j.report)
+}
+
+// csvHeader contains the column names of the CSV output.
+var csvHeader = []string{
+	"database", "iter", "threads", "conns", "run_duration_ns", "rate", "warmup", "seed", "run_id", "version", "commit",
+	"name", "type", "count", "successes", "errors", "start", "end", "duration_ns", "ops_per_sec", "ns_per_op",
+	"avg_ns", "min_ns", "max_ns", "stddev_ns", "p50_ns", "p90_ns", "p95_ns", "p99_ns", "p999_ns",
+}
+
+// csvWriter writes one row for each benchmark, including the run configuration.
+type csvWriter struct {
+	w             *csv.Writer
+	cfg           Config
+	headerWritten bool
+}
+
+// WriteBenchmark writes a row for the benchmark and one for each of its statements.
+func (c *csvWriter) WriteBenchmark(b Benchmark) error {
+	for _, b := range flatten([]Benchmark{b}) {
+		if err := c.writeRow(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) writeRow(b Benchmark) error {
+	if !c.headerWritten {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+
+	d := func(d time.Duration) string { return strconv.FormatInt(d.Nanoseconds(), 10) }
+	u := func(u uint64) string { return strconv.FormatUint(u, 10) }
+
+	err := c.w.Write([]string{
+		c.cfg.Database,
+		strconv.Itoa(c.cfg.Iter),
+		strconv.Itoa(cmp.Or(b.Threads, c.cfg.Threads)),
+		strconv.Itoa(c.cfg.Conns),
+		d(c.cfg.Duration),
+		strconv.FormatFloat(c.cfg.Rate, 'f', -1, 64),
+		c.cfg.Warmup,
+		strconv.FormatUint(c.cfg.Seed, 10),
+		c.cfg.RunID,
+		c.cfg.Version,
+		c.cfg.Commit,
+		b.Name,
+		b.Type,
+		u(b.Count),
+		u(b.Successes),
+		u(b.Errors),
+		b.Start.Format(time.RFC3339Nano),
+		b.End.Format(time.RFC3339Nano),
+		d(b.Duration),
+		strconv.FormatFloat(b.OpsPerSec, 'f', 2, 64),
+		strconv.FormatInt(b.NsPerOp, 10),
+		d(b.Avg),
+		d(b.Min),
+		d(b.Max),
+		d(b.StdDev),
+		d(b.P50),
+		d(b.P90),
+		d(b.P95),
+		d(b.P99),
+		d(b.P999),
+	})
+	if err != nil {
+		return err
+	}
+	// write each row immediately, results of long runs shouldn't get lost
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Flush(time.Duration) error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// markdownWriter writes a configuration and a result table after all benchmarks finished.
+type markdownWriter struct {
+	w      io.Writer
+	report Report
+}
+
+func (m *markdownWriter) WriteBenchmark(b Benchmark) error {
+	m.report.Benchmarks = append(m.report.Benchmarks, b)
+	return nil
+}
+
+func (m *markdownWriter) Flush(total time.Duration) error {
+	cfg := m.report.Config
+
+	if _, err := fmt.Fprintf(m.w, `| database | iter | threads | conns | duration | rate | warmup | seed | run id | version | commit |
+|----------|------|---------|-------|----------|------|--------|------|--------|---------|--------|
+| %v | %v | %v | %v | %v | %v | %v | %v | %v | %v | %v |
+
+| name | threads | count | errors | took | ops/s | avg | min | p50 | p90 | p95 | p99 | p99.9 | max |
+|------|---------|-------|--------|------|-------|-----|-----|-----|-----|-----|-----|-------|-----|
+`, cfg.Database, cfg.Iter, cfg.Threads, cfg.Conns, cfg.Duration, cfg.Rate, cfg.Warmup, cfg.Seed, cfg.RunID, cfg.Version, cfg.Commit); err != nil {
+		return err
+	}
+
+	for _, b := range flatten(m.report.Benchmarks) {
+		if _, err := fmt.Fprintf(m.w, "| %v | %v | %v | %v | %v | %.2f | %v | %v | %v | %v | %v | %v | %v | %v |\n",
+			strings.ReplaceAll(b.Name, "|", `\|`), cmp.Or(b.Threads, cfg.Threads), b.Count, b.Errors, b.Duration, b.OpsPerSec, b.Avg, b.Min, b.P50, b.P90, b.P95, b.P99, b.P999, b.Max); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(m.w, "\ntotal: %v\n", total)
+	return err
+}
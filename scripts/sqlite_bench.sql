@@ -1,5 +1,5 @@
--- Create table
-\benchmark once \name init
+-- Create table, not measured
+\setup
 CREATE TABLE dbbench_simple (id INT PRIMARY KEY, balance DECIMAL);
 
 -- How long takes an insert and delete?
@@ -14,6 +14,6 @@ INSERT INTO dbbench_simple (id, balance) VALUES({{.Iter}}, {{call .RandInt64}});
 DELETE FROM dbbench_simple WHERE id = {{.Iter}}; 
 COMMIT;
 
--- Delete table
-\benchmark once \name clean
+-- Delete table, not measured and also executed when the benchmarks got canceled
+\teardown
 DROP TABLE dbbench_simple;